@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"fmt"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"sort"
+)
+
+// CoverageRow es la mejor efectividad que el equipo consigue contra un tipo
+// defensor puro con sus movimientos de daño revelados.
+type CoverageRow struct {
+	Type  string   `json:"type"`
+	Best  float64  `json:"best"`
+	Moves []string `json:"moves"`
+}
+
+// TeamReport junta la matriz defensiva, la cobertura ofensiva y los avisos
+// de amenazas sin respuesta de un jugador.
+type TeamReport struct {
+	PlayerID   string        `json:"playerId"`
+	Defensive  []TypeRow     `json:"defensive"`
+	Offensive  []CoverageRow `json:"offensive"`
+	Unresisted []string      `json:"unresisted"`
+	Exposed    []string      `json:"exposed"`
+	Uncovered  []string      `json:"uncovered"`
+	NoAnswer   []string      `json:"noAnswer"`
+}
+
+// Coverage calcula, por cada tipo, el mejor movimiento de daño revelado del
+// equipo contra un Pokémon de ese tipo puro.
+func Coverage(dex *data.Dex, members []*game.Pokemon) []CoverageRow {
+	rows := []CoverageRow{}
+	for _, defType := range dex.GetTypeNames() {
+		row := CoverageRow{Type: defType}
+		for _, poke := range members {
+			for _, m := range poke.Moves {
+				move, ok := dex.GetMove(m.Name)
+				if !ok || move.Power <= 0 || move.Category == "Status" {
+					continue
+				}
+				eff := dex.Effectiveness(move.Type, []string{defType}, data.EffectivenessOptions{MoveID: data.ToID(m.Name)})
+				label := fmt.Sprintf("%s (%s)", m.Name, poke.Name)
+				switch {
+				case eff > row.Best:
+					row.Best, row.Moves = eff, []string{label}
+				case eff == row.Best && eff > 0:
+					row.Moves = append(row.Moves, label)
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// BuildTeamReport analiza el equipo conocido de player contra los
+// Pokémon revelados de opponent.
+func BuildTeamReport(dex *data.Dex, player, opponent *game.Player) *TeamReport {
+	members := TeamMembers(player, true)
+	report := &TeamReport{
+		PlayerID:  player.ID,
+		Defensive: DefensiveMatrix(dex, members),
+		Offensive: Coverage(dex, members),
+	}
+	report.Unresisted = Unresisted(report.Defensive)
+	for _, row := range report.Defensive {
+		if row.Weak > 0 && row.Resist == 0 && row.Immune == 0 {
+			report.Exposed = append(report.Exposed, row.Type)
+		}
+	}
+
+	hasMoves := false
+	for _, poke := range members {
+		hasMoves = hasMoves || len(poke.Moves) > 0
+	}
+	if hasMoves {
+		for _, row := range report.Offensive {
+			if row.Best < 1 {
+				report.Uncovered = append(report.Uncovered, row.Type)
+			}
+		}
+	}
+
+	for _, threat := range TeamMembers(opponent, true) {
+		if len(threat.Type) > 0 && !hasAnswer(dex, members, threat) {
+			report.NoAnswer = append(report.NoAnswer, threat.Name)
+		}
+	}
+	sort.Strings(report.NoAnswer)
+	return report
+}
+
+// hasAnswer indica si algún miembro resiste todos los STAB de threat, o
+// bien le pega súper eficaz con un movimiento revelado.
+func hasAnswer(dex *data.Dex, members []*game.Pokemon, threat *game.Pokemon) bool {
+	for _, poke := range members {
+		if len(poke.Type) == 0 {
+			continue
+		}
+		resistsAll := true
+		for _, t := range threat.STABTypes() {
+			if dex.Effectiveness(t, poke.Type, data.EffectivenessOptions{}) >= 1 {
+				resistsAll = false
+			}
+		}
+		if resistsAll {
+			return true
+		}
+		for _, m := range poke.Moves {
+			move, ok := dex.GetMove(m.Name)
+			if !ok || move.Power <= 0 || move.Category == "Status" {
+				continue
+			}
+			if dex.Effectiveness(move.Type, threat.Type, data.EffectivenessOptions{MoveID: data.ToID(m.Name)}) > 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
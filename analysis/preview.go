@@ -0,0 +1,163 @@
+package analysis
+
+import (
+	"math"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/predict"
+	"showdown-analizer/speed"
+	"sort"
+)
+
+type MemberPreview struct {
+	Name      string   `json:"name"`
+	Types     []string `json:"types"`
+	SpeedMin  int      `json:"speedMin"`
+	SpeedMax  int      `json:"speedMax"`
+	LeadScore float64  `json:"leadScore"`
+}
+
+type SidePreview struct {
+	PlayerID    string          `json:"playerId"`
+	Name        string          `json:"name"`
+	Members     []MemberPreview `json:"members"`
+	Matrix      []TypeRow       `json:"matrix"`
+	Unresisted  []string        `json:"unresisted"`
+	LikelyLeads []string        `json:"likelyLeads"`
+}
+
+type TeamPreview struct {
+	Sides           []SidePreview `json:"sides"`
+	RecommendedLead string        `json:"recommendedLead,omitempty"`
+	LeadFor         string        `json:"leadFor,omitempty"`
+}
+
+// Movimientos que justifican salir de lead: hazards, Fake Out y pivotes.
+var leadMoves = map[string]float64{
+	"stealthrock": 1, "spikes": 0.8, "stickyweb": 1, "toxicspikes": 0.5,
+	"fakeout": 0.6, "uturn": 0.4, "voltswitch": 0.4, "taunt": 0.3,
+}
+
+// BuildTeamPreview analiza ambos equipos tal como se ven en team preview (o
+// lo revelado hasta ahora) y recomienda un lead para la perspectiva de
+// state, o para p1 si se mira como espectador.
+func BuildTeamPreview(dex *data.Dex, state *game.BattleState) *TeamPreview {
+	report := &TeamPreview{}
+	for _, player := range state.OrderedPlayers() {
+		members := TeamMembers(player, true)
+		side := SidePreview{
+			PlayerID: player.ID,
+			Name:     player.Name,
+			Matrix:   DefensiveMatrix(dex, members),
+		}
+		side.Unresisted = Unresisted(side.Matrix)
+		for _, poke := range members {
+			r := speed.StatRange(dex, state.Format, poke)
+			side.Members = append(side.Members, MemberPreview{
+				Name:      poke.Name,
+				Types:     poke.Type,
+				SpeedMin:  r.Min,
+				SpeedMax:  r.Max,
+				LeadScore: leadScore(dex, state.Format, poke, r),
+			})
+		}
+		sort.SliceStable(side.Members, func(i, j int) bool {
+			return side.Members[i].SpeedMax > side.Members[j].SpeedMax
+		})
+		side.LikelyLeads = likelyLeads(side.Members, 2)
+		report.Sides = append(report.Sides, side)
+	}
+
+	self, opponent := state.Self(), state.Opponent()
+	if self == nil {
+		self, opponent = state.Players["p1"], state.Players["p2"]
+	}
+	if self != nil && opponent != nil {
+		report.LeadFor = self.ID
+		report.RecommendedLead = recommendLead(dex, state.Format, self, opponent)
+	}
+	return report
+}
+
+func leadScore(dex *data.Dex, format string, poke *game.Pokemon, r speed.Range) float64 {
+	score := math.Min(float64(r.Max)/400, 1)
+	moves := map[string]float64{}
+	for _, m := range poke.Moves {
+		moves[data.ToID(m.Name)] = 1
+	}
+	if pred := predict.For(dex, format, poke); pred != nil {
+		for _, c := range pred.Moves {
+			moves[data.ToID(c.Name)] = math.Max(moves[data.ToID(c.Name)], c.Prob)
+		}
+	}
+	for id, p := range moves {
+		score += leadMoves[id] * p
+	}
+	return score
+}
+
+func likelyLeads(members []MemberPreview, n int) []string {
+	sorted := append([]MemberPreview(nil), members...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].LeadScore > sorted[j].LeadScore })
+	leads := []string{}
+	for i := 0; i < len(sorted) && i < n; i++ {
+		leads = append(leads, sorted[i].Name)
+	}
+	return leads
+}
+
+// recommendLead elige el miembro de self con mejor enfrentamiento promedio
+// contra el equipo rival, ponderado por la probabilidad de que cada rival
+// salga de lead.
+func recommendLead(dex *data.Dex, format string, self, opponent *game.Player) string {
+	rivals := TeamMembers(opponent, true)
+	if len(rivals) == 0 {
+		return ""
+	}
+	weights := make([]float64, len(rivals))
+	for i, rival := range rivals {
+		weights[i] = 0.5 + leadScore(dex, format, rival, speed.StatRange(dex, format, rival))
+	}
+
+	best, bestScore := "", math.Inf(-1)
+	for _, poke := range TeamMembers(self, true) {
+		total, weightSum := 0.0, 0.0
+		for i, rival := range rivals {
+			total += Matchup(dex, format, poke, rival) * weights[i]
+			weightSum += weights[i]
+		}
+		if score := total / weightSum; score > bestScore {
+			best, bestScore = poke.Name, score
+		}
+	}
+	return best
+}
+
+// Matchup puntúa a contra b usando sólo tipos y velocidad: positivo si a
+// pega más fuerte de lo que recibe.
+func Matchup(dex *data.Dex, format string, a, b *game.Pokemon) float64 {
+	offense := bestSTAB(dex, a, b)
+	defense := bestSTAB(dex, b, a)
+	score := math.Log2(math.Max(offense, 0.125)) - math.Log2(math.Max(defense, 0.125))
+
+	ra, rb := speed.StatRange(dex, format, a), speed.StatRange(dex, format, b)
+	if ra.Known() && rb.Known() {
+		if ra.Min > rb.Max {
+			score += 0.5
+		} else if rb.Min > ra.Max {
+			score -= 0.5
+		}
+	}
+	return score
+}
+
+func bestSTAB(dex *data.Dex, attacker, defender *game.Pokemon) float64 {
+	best := 1.0
+	for i, t := range attacker.Type {
+		eff := dex.Effectiveness(t, defender.Type, data.EffectivenessOptions{})
+		if i == 0 || eff > best {
+			best = eff
+		}
+	}
+	return best
+}
@@ -0,0 +1,184 @@
+package analysis
+
+import (
+	"math"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/infer"
+	"showdown-analizer/predict"
+	"showdown-analizer/speed"
+	"sort"
+)
+
+// Por debajo de esta probabilidad un movimiento predicho del rival no se
+// tiene en cuenta al evaluar cambios.
+const minThreatProb = 0.3
+
+// UnknownSTABDamage es el daño de un STAB neutro que se asume sin
+// movimientos conocidos, como fracción de la vida máxima.
+const UnknownSTABDamage = 0.3
+
+// SwitchOption es un Pokémon del banco evaluado como cambio contra el activo
+// rival. Las fracciones son sobre la vida máxima del que entra.
+type SwitchOption struct {
+	Pokemon      *game.Pokemon `json:"-"`
+	Name         string        `json:"name"`
+	Score        float64       `json:"score"`
+	HP           float64       `json:"hp"`
+	HazardDamage float64       `json:"hazardDamage"`
+	DamageTaken  float64       `json:"damageTaken"`
+	WorstMove    string        `json:"worstMove,omitempty"`
+	Threat       float64       `json:"threat"`
+	BestMove     string        `json:"bestMove,omitempty"`
+	Outspeeds    int           `json:"outspeeds"`
+	Notes        []string      `json:"notes,omitempty"`
+}
+
+// EvaluateSwitches ordena los cambios posibles de player contra el activo
+// de opponent, de mejor a peor. Cada opción resta a la vida actual el daño
+// de hazards al entrar y el peor golpe esperado del rival, y suma el daño
+// que el que entra puede devolver.
+func EvaluateSwitches(dex *data.Dex, state *game.BattleState, player, opponent *game.Player) []SwitchOption {
+	if player == nil || opponent == nil || opponent.Active == nil {
+		return nil
+	}
+	foe := opponent.Active
+	foeMoves := threatMoves(dex, state.Format, foe)
+	foeSpeed := speed.Effective(dex, state, opponent)
+
+	options := []SwitchOption{}
+	for _, poke := range TeamMembers(player, true) {
+		if poke == player.Active || len(poke.Type) == 0 {
+			continue
+		}
+		opt := SwitchOption{Pokemon: poke, Name: poke.Name, HP: 1}
+		if poke.MaxHP > 0 {
+			opt.HP = float64(poke.HP) / float64(poke.MaxHP)
+		}
+		opt.HazardDamage, opt.Notes = HazardDamage(dex, player, poke)
+		opt.DamageTaken, opt.WorstMove = worstHit(dex, state, foe, poke, foeMoves)
+		opt.Threat, opt.BestMove = worstHit(dex, state, poke, foe, threatMoves(dex, state.Format, poke))
+
+		mine := EntrySpeed(dex, state, player, poke)
+		if mine.Known() && foeSpeed.Known() {
+			switch {
+			case mine.Min > foeSpeed.Max:
+				opt.Outspeeds = 1
+			case mine.Max < foeSpeed.Min:
+				opt.Outspeeds = -1
+			}
+			if speed.TrickRoom(state) {
+				opt.Outspeeds = -opt.Outspeeds
+			}
+		}
+
+		remaining := opt.HP - opt.HazardDamage - opt.DamageTaken
+		opt.Score = remaining + 0.5*math.Min(opt.Threat, 1) + 0.1*float64(opt.Outspeeds)
+		if remaining <= 0 {
+			opt.Score -= 1
+		}
+		options = append(options, opt)
+	}
+	sort.SliceStable(options, func(i, j int) bool { return options[i].Score > options[j].Score })
+	return options
+}
+
+// EntrySpeed es la Speed real de poke al entrar desde el banco de player,
+// con el -1 de Sticky Web si lo toca.
+func EntrySpeed(dex *data.Dex, state *game.BattleState, player *game.Player, poke *game.Pokemon) speed.Range {
+	stickyWeb := player.SideConditions["Sticky Web"] > 0 && Grounded(poke) && data.ToID(poke.Item) != "heavydutyboots"
+	return speed.OnEntry(dex, state, player, poke, stickyWeb)
+}
+
+// HazardDamage calcula la fracción de vida que poke pierde al entrar por los
+// hazards del lado de player, y avisa de los que no quitan vida.
+func HazardDamage(dex *data.Dex, player *game.Player, poke *game.Pokemon) (float64, []string) {
+	if data.ToID(poke.Item) == "heavydutyboots" {
+		return 0, nil
+	}
+	var notes []string
+	damage := 0.0
+	if player.SideConditions["Stealth Rock"] > 0 && poke.Ability != "Magic Guard" {
+		damage += dex.Effectiveness("Rock", poke.Type, data.EffectivenessOptions{}) / 8
+	}
+	if Grounded(poke) {
+		if poke.Ability != "Magic Guard" {
+			switch layers := player.SideConditions["Spikes"]; {
+			case layers >= 3:
+				damage += 1.0 / 4
+			case layers == 2:
+				damage += 1.0 / 6
+			case layers == 1:
+				damage += 1.0 / 8
+			}
+		}
+		if player.SideConditions["Toxic Spikes"] > 0 && poke.Status == "" && !hasType(poke, "Poison") && !hasType(poke, "Steel") {
+			notes = append(notes, "se envenena con Toxic Spikes")
+		}
+		if player.SideConditions["Sticky Web"] > 0 {
+			notes = append(notes, "-1 Spe por Sticky Web")
+		}
+	}
+	return damage, notes
+}
+
+// Grounded indica si a poke le afectan Spikes, Toxic Spikes y Sticky Web.
+func Grounded(poke *game.Pokemon) bool {
+	return !hasType(poke, "Flying") && poke.Ability != "Levitate" && data.ToID(poke.Item) != "airballoon"
+}
+
+func hasType(poke *game.Pokemon, t string) bool {
+	for _, pt := range poke.Type {
+		if pt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// threatMoves devuelve los movimientos revelados de poke y los predichos con
+// probabilidad suficiente.
+func threatMoves(dex *data.Dex, format string, poke *game.Pokemon) []string {
+	moves := []string{}
+	for _, m := range poke.Moves {
+		moves = append(moves, m.Name)
+	}
+	if len(poke.Moves) < 4 {
+		if pred := predict.For(dex, format, poke); pred != nil {
+			for _, c := range pred.Moves {
+				if c.Prob >= minThreatProb {
+					moves = append(moves, c.Name)
+				}
+			}
+		}
+	}
+	return moves
+}
+
+// worstHit devuelve el golpe más fuerte de attacker contra defender entre
+// moves, como punto medio del rango estimado. Si no se puede estimar
+// ninguno se usa el mejor STAB con un daño nominal.
+func worstHit(dex *data.Dex, state *game.BattleState, attacker, defender *game.Pokemon, moves []string) (float64, string) {
+	best, bestMove := 0.0, ""
+	estimated := false
+	for _, move := range moves {
+		lo, hi, ok := infer.EstimateDamage(dex, state, attacker, defender, move)
+		if !ok {
+			continue
+		}
+		estimated = true
+		if mid := (lo + hi) / 2; mid > best {
+			best, bestMove = mid, move
+		}
+	}
+	if estimated {
+		return best, bestMove
+	}
+	for _, t := range attacker.STABTypes() {
+		eff := dex.Effectiveness(t, defender.Type, data.EffectivenessOptions{})
+		if d := eff * UnknownSTABDamage; d > best {
+			best, bestMove = d, ""
+		}
+	}
+	return best, bestMove
+}
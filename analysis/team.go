@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"sort"
+)
+
+// TypeRow resume cómo recibe un equipo los ataques de un tipo: cuántos
+// miembros son débiles, resisten o son inmunes.
+type TypeRow struct {
+	Type   string             `json:"type"`
+	Weak   int                `json:"weak"`
+	Resist int                `json:"resist"`
+	Immune int                `json:"immune"`
+	ByName map[string]float64 `json:"byName"`
+}
+
+// DefensiveMatrix calcula una fila por tipo atacante para members.
+func DefensiveMatrix(dex *data.Dex, members []*game.Pokemon) []TypeRow {
+	rows := []TypeRow{}
+	for _, attackType := range dex.GetTypeNames() {
+		row := TypeRow{Type: attackType, ByName: map[string]float64{}}
+		for _, poke := range members {
+			if len(poke.Type) == 0 {
+				continue
+			}
+			eff := dex.Effectiveness(attackType, poke.Type, data.EffectivenessOptions{})
+			row.ByName[poke.Name] = eff
+			switch {
+			case eff == 0:
+				row.Immune++
+			case eff < 1:
+				row.Resist++
+			case eff > 1:
+				row.Weak++
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Unresisted devuelve los tipos atacantes que ningún miembro resiste ni
+// bloquea.
+func Unresisted(rows []TypeRow) []string {
+	var types []string
+	for _, row := range rows {
+		if row.Resist == 0 && row.Immune == 0 {
+			types = append(types, row.Type)
+		}
+	}
+	return types
+}
+
+// TeamMembers devuelve el equipo conocido de player ordenado por nombre,
+// sin los debilitados si aliveOnly es true.
+func TeamMembers(player *game.Player, aliveOnly bool) []*game.Pokemon {
+	members := []*game.Pokemon{}
+	if player == nil {
+		return members
+	}
+	for _, poke := range player.Team {
+		if aliveOnly && poke.Fainted {
+			continue
+		}
+		members = append(members, poke)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	return members
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"showdown-analizer/client"
+	"showdown-analizer/data"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cuánto se reutiliza una roomlist antes de volver a pedirla.
+const roomListTTL = 15 * time.Second
+
+// Conexiones a Showdown abiertas a la vez para pedir roomlists.
+const maxRoomListFetches = 4
+
+type battleListing struct {
+	RoomID string `json:"roomid"`
+	Format string `json:"format"`
+	P1     string `json:"p1"`
+	P2     string `json:"p2"`
+	Rating int    `json:"rating"`
+}
+
+type cachedRoomList struct {
+	fetched time.Time
+	list    client.RoomList
+}
+
+// roomListCall es un pedido de roomlist en curso; los que piden la misma
+// mientras tanto esperan done y comparten el resultado.
+type roomListCall struct {
+	done chan struct{}
+	list client.RoomList
+	err  error
+}
+
+var (
+	roomListMu       sync.Mutex
+	roomListCache    = map[string]cachedRoomList{}
+	roomListInFlight = map[string]*roomListCall{}
+	roomListSlots    = make(chan struct{}, maxRoomListFetches)
+)
+
+// fetchRoomList abre una conexión aparte para pedir la roomlist, para no
+// mezclar la respuesta con los mensajes de las salas que se están
+// siguiendo. Los pedidos iguales simultáneos comparten una conexión y no
+// hay más de maxRoomListFetches abiertas.
+func fetchRoomList(format string, minElo int) (client.RoomList, error) {
+	key := fmt.Sprintf("%s,%d", format, minElo)
+	roomListMu.Lock()
+	if cached, ok := roomListCache[key]; ok && time.Since(cached.fetched) < roomListTTL {
+		roomListMu.Unlock()
+		return cached.list, nil
+	}
+	if call, ok := roomListInFlight[key]; ok {
+		roomListMu.Unlock()
+		<-call.done
+		return call.list, call.err
+	}
+	call := &roomListCall{done: make(chan struct{})}
+	roomListInFlight[key] = call
+	roomListMu.Unlock()
+
+	roomListSlots <- struct{}{}
+	call.list, call.err = requestRoomList(format, minElo)
+	<-roomListSlots
+
+	roomListMu.Lock()
+	delete(roomListInFlight, key)
+	if call.err == nil {
+		evictRoomLists()
+		roomListCache[key] = cachedRoomList{fetched: time.Now(), list: call.list}
+	}
+	roomListMu.Unlock()
+	close(call.done)
+	return call.list, call.err
+}
+
+func requestRoomList(format string, minElo int) (client.RoomList, error) {
+	sc, err := client.NewShowdownClient(showdown)
+	if err != nil {
+		return client.RoomList{}, err
+	}
+	defer sc.Conn.Close()
+	return sc.RoomList(format, minElo)
+}
+
+// evictRoomLists borra las roomlists vencidas. Se llama con roomListMu.
+func evictRoomLists() {
+	for key, cached := range roomListCache {
+		if time.Since(cached.fetched) >= roomListTTL {
+			delete(roomListCache, key)
+		}
+	}
+}
+
+// handleBattles lista las batallas en curso filtradas por formato, rating
+// mínimo y nombre de jugador. Devuelve HTML para la UI o JSON con
+// ?json=1.
+func handleBattles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := data.ToID(query.Get("format"))
+	user := data.ToID(query.Get("user"))
+	minElo, _ := strconv.Atoi(query.Get("minelo"))
+
+	list, err := fetchRoomList(format, minElo)
+	if err != nil {
+		log.Printf("Error pidiendo roomlist: %v", err)
+		http.Error(w, fmt.Sprintf("Error pidiendo batallas a Showdown: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	battles := []battleListing{}
+	for roomID, entry := range list.Rooms {
+		if user != "" && !strings.Contains(data.ToID(entry.P1), user) && !strings.Contains(data.ToID(entry.P2), user) {
+			continue
+		}
+		battles = append(battles, battleListing{
+			RoomID: roomID,
+			Format: formatFromRoomID(roomID),
+			P1:     entry.P1,
+			P2:     entry.P2,
+			Rating: entry.MinElo,
+		})
+	}
+	sort.Slice(battles, func(i, j int) bool {
+		if battles[i].Rating != battles[j].Rating {
+			return battles[i].Rating > battles[j].Rating
+		}
+		return battles[i].RoomID < battles[j].RoomID
+	})
+
+	if query.Get("json") != "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(battles)
+		return
+	}
+	if err := templates.ExecuteTemplate(w, "battles.html", battles); err != nil {
+		log.Printf("Error renderizando batallas: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+// Package bot juega partidas solo usando el motor de sugerencias: sirve para
+// enfrentar heurísticas entre sí en un servidor de Showdown local.
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"showdown-analizer/client"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/parser"
+	"showdown-analizer/search"
+	"strings"
+)
+
+type Config struct {
+	// Format es el formato en el que se busca partida y se aceptan desafíos.
+	Format string
+	// Games es cuántas partidas jugar antes de terminar; 0 no tiene límite.
+	Games int
+	// Ladder busca partida en el ladder; si no, sólo acepta desafíos.
+	Ladder bool
+	// Accept limita los desafíos aceptados a un usuario; vacío acepta a
+	// cualquiera.
+	Accept string
+	// Team es el equipo empaquetado para formatos que no lo generan.
+	Team   string
+	Search search.Options
+	// Dex son los datos con que se juega; cada batalla usa la vista de su
+	// generación.
+	Dex *data.Dex
+}
+
+type battle struct {
+	state    *game.BattleState
+	gen      int
+	answered int
+}
+
+type Runner struct {
+	client  *client.ShowdownClient
+	cfg     Config
+	battles map[string]*battle
+	played  int
+}
+
+// New arma un runner sobre una conexión ya logueada.
+func New(c *client.ShowdownClient, cfg Config) *Runner {
+	return &Runner{client: c, cfg: cfg, battles: map[string]*battle{}}
+}
+
+// Run juega hasta completar cfg.Games partidas o hasta que se cancele ctx.
+func (r *Runner) Run(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		r.client.Conn.Close()
+	}()
+
+	if r.cfg.Team != "" {
+		if err := r.client.UploadTeam(r.cfg.Team); err != nil {
+			return err
+		}
+	}
+	if r.cfg.Ladder {
+		if err := r.client.Search(r.cfg.Format); err != nil {
+			return err
+		}
+	}
+
+	for {
+		_, message, err := r.client.Conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("error leyendo de showdown: %w", err)
+		}
+		done, err := r.handle(string(message))
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// handle procesa un mensaje del servidor. Devuelve true cuando ya se
+// jugaron todas las partidas pedidas.
+func (r *Runner) handle(message string) (bool, error) {
+	room, lines := client.ParseMessage(message)
+	if !strings.HasPrefix(room, "battle-") {
+		for _, line := range lines {
+			ev, ok, err := client.ParseEvent(line)
+			if err != nil {
+				log.Printf("[Bot] %v", err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			switch ev := ev.(type) {
+			case client.ChallengesUpdate:
+				if err := r.acceptChallenges(ev); err != nil {
+					return false, err
+				}
+			case client.Popup:
+				log.Printf("[Bot] popup: %s", ev.Message)
+			}
+		}
+		return false, nil
+	}
+
+	b, ok := r.battles[room]
+	if !ok {
+		b = &battle{state: game.NewBattleState()}
+		b.state.Format, _, _ = strings.Cut(strings.TrimPrefix(room, "battle-"), "-")
+		b.gen = data.GenFromFormat(b.state.Format)
+		r.battles[room] = b
+		log.Printf("[Bot] entrando a %s", room)
+	}
+	dex := r.cfg.Dex.ForGen(b.gen)
+
+	act, finished := false, false
+	for _, line := range lines {
+		parser.ProcessLine(dex, b.state, line)
+		switch {
+		case strings.HasPrefix(line, "|turn|"), strings.HasPrefix(line, "|teampreview"):
+			act = true
+		case strings.HasPrefix(line, "|win|"), line == "|tie":
+			finished = true
+		case strings.HasPrefix(line, "|error|[Invalid choice]"), strings.HasPrefix(line, "|error|[Unavailable choice]"):
+			log.Printf("[Bot] %s: %s, se elige por defecto", room, line)
+			if err := r.choose(room, b, "default"); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if finished {
+		return r.finish(room, b)
+	}
+	if self := b.state.Self(); self != nil && self.Request != nil {
+		req := self.Request
+		if req.Wait || req.RQID == b.answered {
+			return false, nil
+		}
+		forced := req.TeamPreview
+		for _, f := range req.ForceSwitch {
+			forced = forced || f
+		}
+		if act || forced {
+			return false, r.choose(room, b, Decide(dex, b.state, req, r.cfg.Search))
+		}
+	}
+	return false, nil
+}
+
+func (r *Runner) choose(room string, b *battle, choice string) error {
+	rqid := 0
+	if self := b.state.Self(); self != nil && self.Request != nil {
+		rqid = self.Request.RQID
+	}
+	b.answered = rqid
+	log.Printf("[Bot] %s turno %d: %s", room, b.state.Turn, choice)
+	return r.client.Choose(room, choice, rqid)
+}
+
+func (r *Runner) finish(room string, b *battle) (bool, error) {
+	delete(r.battles, room)
+	r.played++
+	result := "empate"
+	if b.state.Winner != "" {
+		result = "perdió"
+		if b.state.Winner == b.state.Perspective {
+			result = "ganó"
+		}
+	}
+	log.Printf("[Bot] %s terminada en %d turnos: %s (%d jugadas)", room, b.state.Turn, result, r.played)
+	if err := r.client.LeaveRoom(room); err != nil {
+		return false, err
+	}
+	if r.cfg.Games > 0 && r.played >= r.cfg.Games {
+		return true, nil
+	}
+	if r.cfg.Ladder {
+		return false, r.client.Search(r.cfg.Format)
+	}
+	return false, nil
+}
+
+// acceptChallenges acepta los desafíos pendientes en el formato configurado.
+func (r *Runner) acceptChallenges(update client.ChallengesUpdate) error {
+	for user, format := range update.ChallengesFrom {
+		if format != r.cfg.Format || (r.cfg.Accept != "" && data.ToID(user) != data.ToID(r.cfg.Accept)) {
+			continue
+		}
+		log.Printf("[Bot] aceptando desafío de %s en %s", user, format)
+		if err := r.client.Accept(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
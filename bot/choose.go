@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"fmt"
+	"showdown-analizer/analysis"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/search"
+	"strings"
+)
+
+// Decide elige la respuesta a req con el motor de sugerencias y la devuelve
+// en el formato de /choose ("move 2", "switch 3", "team 4" o "default").
+func Decide(dex *data.Dex, state *game.BattleState, req *game.Request, opts search.Options) string {
+	self, opponent := state.Self(), state.Opponent()
+	if self == nil {
+		return "default"
+	}
+
+	if req.TeamPreview {
+		lead := analysis.BuildTeamPreview(dex, state).RecommendedLead
+		if i := slotOf(req, lead, false); i > 0 {
+			return fmt.Sprintf("team %d", i)
+		}
+		return "default"
+	}
+
+	for _, forced := range req.ForceSwitch {
+		if !forced {
+			continue
+		}
+		for _, opt := range analysis.EvaluateSwitches(dex, state, self, opponent) {
+			if i := slotOf(req, opt.Name, true); i > 0 {
+				return fmt.Sprintf("switch %d", i)
+			}
+		}
+		return firstSwitch(req)
+	}
+
+	if len(req.Active) == 0 {
+		return "default"
+	}
+	active := req.Active[0]
+	for _, result := range search.Search(dex, state, self, opponent, opts).Results {
+		if result.Choice.Switch {
+			if active.Trapped {
+				continue
+			}
+			if i := slotOf(req, result.Choice.Name, true); i > 0 {
+				return fmt.Sprintf("switch %d", i)
+			}
+			continue
+		}
+		if i := moveSlot(active, result.Choice.Name); i > 0 {
+			return fmt.Sprintf("move %d", i)
+		}
+	}
+	for i, m := range active.Moves {
+		if !m.Disabled && m.PP > 0 {
+			return fmt.Sprintf("move %d", i+1)
+		}
+	}
+	return "default"
+}
+
+// moveSlot es la posición (desde 1) de name entre los movimientos
+// seleccionables, o 0.
+func moveSlot(active game.ActiveRequest, name string) int {
+	for i, m := range active.Moves {
+		if (m.ID == data.ToID(name) || m.Move == name) && !m.Disabled && m.PP > 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// slotOf es la posición (desde 1) de name en el equipo del request, o 0. Con
+// forSwitch sólo vale si puede entrar: no está activo ni debilitado.
+func slotOf(req *game.Request, name string, forSwitch bool) int {
+	if name == "" {
+		return 0
+	}
+	for i, rp := range req.Side.Pokemon {
+		_, ident, _ := strings.Cut(rp.Ident, ": ")
+		if ident != name {
+			continue
+		}
+		if forSwitch && (rp.Active || strings.HasSuffix(rp.Condition, " fnt")) {
+			return 0
+		}
+		return i + 1
+	}
+	return 0
+}
+
+func firstSwitch(req *game.Request) string {
+	for i, rp := range req.Side.Pokemon {
+		if !rp.Active && !strings.HasSuffix(rp.Condition, " fnt") {
+			return fmt.Sprintf("switch %d", i+1)
+		}
+	}
+	return "default"
+}
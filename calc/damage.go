@@ -0,0 +1,92 @@
+package calc
+
+import "math"
+
+// DamageInput reúne todo lo que necesita la fórmula de daño de gen 5 en
+// adelante. Weather es el multiplicador de clima, que se aplica antes del
+// crítico; Modifier agrupa los multiplicadores finales (objeto,
+// habilidades) que no tienen un campo propio. Cero en cualquiera de los dos
+// es lo mismo que 1.
+type DamageInput struct {
+	Level         int
+	Power         int
+	Attack        int
+	Defense       int
+	STAB          float64
+	Effectiveness float64
+	Weather       float64
+	Modifier      float64
+	Crit          bool
+	Burned        bool
+}
+
+// Damage devuelve el daño mínimo y máximo de las 16 tiradas aleatorias.
+func Damage(in DamageInput) (int, int) {
+	if in.Power <= 0 || in.Effectiveness == 0 || in.Defense <= 0 {
+		return 0, 0
+	}
+	return damageRoll(in, 85), damageRoll(in, 100)
+}
+
+// DamageRoll calcula el daño con una tirada concreta, entre 85 y 100.
+func DamageRoll(in DamageInput, roll int) int {
+	if in.Power <= 0 || in.Effectiveness == 0 || in.Defense <= 0 {
+		return 0
+	}
+	return damageRoll(in, roll)
+}
+
+func damageRoll(in DamageInput, roll int) int {
+	base := (2*in.Level/5+2)*in.Power*in.Attack/in.Defense/50 + 2
+	d := float64(base)
+	if in.Weather > 0 {
+		d = modify(d, in.Weather)
+	}
+	if in.Crit {
+		d = math.Floor(d * 1.5)
+	}
+	d = math.Floor(d * float64(roll) / 100)
+	if in.STAB > 0 {
+		d = modify(d, in.STAB)
+	}
+	d = math.Floor(d * in.Effectiveness)
+	if in.Burned {
+		d = modify(d, 0.5)
+	}
+	if in.Modifier > 0 {
+		d = modify(d, in.Modifier)
+	}
+	if d < 1 {
+		d = 1
+	}
+	return int(d)
+}
+
+// modify aplica un multiplicador como el servidor: en punto fijo sobre
+// 4096 y redondeando a la baja sólo las mitades exactas.
+func modify(d, mul float64) float64 {
+	m := math.Floor(mul * 4096)
+	return math.Floor((d*m + 2047) / 4096)
+}
+
+// WeatherModifier es el multiplicador de clima para un movimiento del tipo
+// moveType.
+func WeatherModifier(weather, moveType string) float64 {
+	switch weather {
+	case "SunnyDay", "DesolateLand":
+		switch moveType {
+		case "Fire":
+			return 1.5
+		case "Water":
+			return 0.5
+		}
+	case "RainDance", "PrimordialSea":
+		switch moveType {
+		case "Water":
+			return 1.5
+		case "Fire":
+			return 0.5
+		}
+	}
+	return 1
+}
@@ -0,0 +1,73 @@
+package calc
+
+import "testing"
+
+// Los valores esperados son los del calculador de daño de Showdown.
+// garchompEQ es Earthquake de un Garchomp Jolly 252 Atk (359) contra un
+// Heatran 0 HP / 0 Def (248 Def, 323 HP).
+var garchompEQ = DamageInput{Level: 100, Power: 100, Attack: 359, Defense: 248, STAB: 1.5, Effectiveness: 4}
+
+func TestDamage(t *testing.T) {
+	with := func(f func(*DamageInput)) DamageInput {
+		in := garchompEQ
+		f(&in)
+		return in
+	}
+	// Fire Blast de Heatran Modest 252 SpA (394) a Garchomp 0 HP / 4 SpD (207).
+	fireBlast := DamageInput{Level: 100, Power: 110, Attack: 394, Defense: 207, STAB: 1.5, Effectiveness: 0.5}
+
+	tests := []struct {
+		name     string
+		in       DamageInput
+		min, max int
+	}{
+		{"stab y x4", garchompEQ, 624, 736},
+		{"life orb", with(func(in *DamageInput) { in.Modifier = 1.3 }), 811, 957},
+		{"crítico", with(func(in *DamageInput) { in.Crit = true }), 936, 1104},
+		{"quemado", with(func(in *DamageInput) { in.Burned = true }), 312, 368},
+		{"resistido", fireBlast, 112, 132},
+		{"sol", func() DamageInput { in := fireBlast; in.Weather = 1.5; return in }(), 168, 198},
+		{"inmune", with(func(in *DamageInput) { in.Effectiveness = 0 }), 0, 0},
+		{"sin potencia", with(func(in *DamageInput) { in.Power = 0 }), 0, 0},
+		{"mínimo 1", DamageInput{Level: 1, Power: 10, Attack: 5, Defense: 500, Effectiveness: 0.25}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			min, max := Damage(tt.in)
+			if min != tt.min || max != tt.max {
+				t.Errorf("Damage = %d-%d, se esperaba %d-%d", min, max, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestDamageRoll(t *testing.T) {
+	want := []int{624, 628, 640, 648, 652, 660, 664, 676, 684, 688, 696, 708, 712, 720, 724, 736}
+	for i, w := range want {
+		roll := 85 + i
+		if got := DamageRoll(garchompEQ, roll); got != w {
+			t.Errorf("DamageRoll(%d) = %d, se esperaba %d", roll, got, w)
+		}
+	}
+}
+
+func TestWeatherModifier(t *testing.T) {
+	tests := []struct {
+		weather, moveType string
+		want              float64
+	}{
+		{"SunnyDay", "Fire", 1.5},
+		{"SunnyDay", "Water", 0.5},
+		{"DesolateLand", "Fire", 1.5},
+		{"RainDance", "Water", 1.5},
+		{"RainDance", "Fire", 0.5},
+		{"PrimordialSea", "Grass", 1},
+		{"Sandstorm", "Rock", 1},
+		{"", "Fire", 1},
+	}
+	for _, tt := range tests {
+		if got := WeatherModifier(tt.weather, tt.moveType); got != tt.want {
+			t.Errorf("WeatherModifier(%q, %q) = %v, se esperaba %v", tt.weather, tt.moveType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package calc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Spread es un reparto de EVs con su naturaleza, como los de las
+// estadísticas de Smogon: "Jolly:0/252/0/0/4/252".
+type Spread struct {
+	Nature string
+	EVs    map[string]int
+}
+
+var spreadStats = []string{"hp", "atk", "def", "spa", "spd", "spe"}
+
+func ParseSpread(s string) (Spread, bool) {
+	nature, evs, ok := strings.Cut(s, ":")
+	parts := strings.Split(evs, "/")
+	if !ok || len(parts) != len(spreadStats) {
+		return Spread{}, false
+	}
+	spread := Spread{Nature: nature, EVs: make(map[string]int, len(spreadStats))}
+	for i, part := range parts {
+		ev, err := strconv.Atoi(part)
+		if err != nil || ev < 0 || ev > MaxEV {
+			return Spread{}, false
+		}
+		spread.EVs[spreadStats[i]] = ev
+	}
+	return spread, true
+}
+
+// Value es el stat final con este reparto y 31 IVs.
+func (s Spread) Value(base, level int, stat string) int {
+	if stat == "hp" {
+		return HP(base, MaxIV, s.EVs[stat], level)
+	}
+	return Stat(base, MaxIV, s.EVs[stat], level, NatureMultiplier(s.Nature, stat))
+}
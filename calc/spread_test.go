@@ -0,0 +1,39 @@
+package calc
+
+import "testing"
+
+func TestParseSpread(t *testing.T) {
+	s, ok := ParseSpread("Jolly:0/252/0/0/4/252")
+	if !ok {
+		t.Fatal("no se pudo leer un reparto válido")
+	}
+	if s.Nature != "Jolly" || s.EVs["atk"] != 252 || s.EVs["spd"] != 4 || s.EVs["spe"] != 252 {
+		t.Errorf("reparto leído mal: %+v", s)
+	}
+
+	for _, bad := range []string{"", "Jolly", "Jolly:0/252/0/0/4", "Jolly:0/252/0/0/4/x", "Jolly:0/253/0/0/4/252", "Jolly:0/-4/0/0/4/252"} {
+		if _, ok := ParseSpread(bad); ok {
+			t.Errorf("ParseSpread(%q) debería fallar", bad)
+		}
+	}
+}
+
+func TestSpreadValue(t *testing.T) {
+	s, _ := ParseSpread("Jolly:0/252/0/0/4/252")
+	tests := []struct {
+		stat string
+		base int
+		want int
+	}{
+		{"hp", 108, 357},
+		{"atk", 130, 359},
+		{"spa", 80, 176},
+		{"spd", 85, 207},
+		{"spe", 102, 333},
+	}
+	for _, tt := range tests {
+		if got := s.Value(tt.base, 100, tt.stat); got != tt.want {
+			t.Errorf("Value(%s) = %d, se esperaba %d", tt.stat, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package calc
+
+import "math"
+
+const (
+	MaxIV = 31
+	MaxEV = 252
+
+	// Random Battle reparte 85 EVs y 31 IVs en todo, con naturaleza neutra.
+	RandomBattleEV = 85
+)
+
+var naturePlus = map[string]string{
+	"Lonely": "atk", "Brave": "atk", "Adamant": "atk", "Naughty": "atk",
+	"Bold": "def", "Relaxed": "def", "Impish": "def", "Lax": "def",
+	"Timid": "spe", "Hasty": "spe", "Jolly": "spe", "Naive": "spe",
+	"Modest": "spa", "Mild": "spa", "Quiet": "spa", "Rash": "spa",
+	"Calm": "spd", "Gentle": "spd", "Sassy": "spd", "Careful": "spd",
+}
+
+var natureMinus = map[string]string{
+	"Lonely": "def", "Brave": "spe", "Adamant": "spa", "Naughty": "spd",
+	"Bold": "atk", "Relaxed": "spe", "Impish": "spa", "Lax": "spd",
+	"Timid": "atk", "Hasty": "def", "Jolly": "spa", "Naive": "spd",
+	"Modest": "atk", "Mild": "def", "Quiet": "spe", "Rash": "spd",
+	"Calm": "atk", "Gentle": "def", "Sassy": "spe", "Careful": "spa",
+}
+
+// NatureMultiplier devuelve 1.1, 0.9 o 1 para stat según la naturaleza.
+func NatureMultiplier(nature, stat string) float64 {
+	switch stat {
+	case naturePlus[nature]:
+		return 1.1
+	case natureMinus[nature]:
+		return 0.9
+	}
+	return 1
+}
+
+func HP(base, iv, ev, level int) int {
+	if base == 1 {
+		return 1 // Shedinja
+	}
+	return (2*base+iv+ev/4)*level/100 + level + 10
+}
+
+func Stat(base, iv, ev, level int, nature float64) int {
+	raw := (2*base+iv+ev/4)*level/100 + 5
+	return raw * int(math.Round(nature*100)) / 100
+}
+
+// BoostMultiplier es el factor de una etapa de boost de -6 a +6 para
+// atk/def/spa/spd/spe.
+func BoostMultiplier(stage int) float64 {
+	if stage > 6 {
+		stage = 6
+	} else if stage < -6 {
+		stage = -6
+	}
+	if stage >= 0 {
+		return float64(2+stage) / 2
+	}
+	return 2 / float64(2-stage)
+}
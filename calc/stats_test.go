@@ -0,0 +1,75 @@
+package calc
+
+import "testing"
+
+func TestStat(t *testing.T) {
+	tests := []struct {
+		name                string
+		base, iv, ev, level int
+		nature              float64
+		want                int
+	}{
+		{"garchomp jolly 252 spe", 102, 31, 252, 100, 1.1, 333},
+		{"garchomp jolly 252 atk", 130, 31, 252, 100, 1, 359},
+		{"heatran modest 252 spa", 130, 31, 252, 100, 1.1, 394},
+		{"heatran 0 def", 106, 31, 0, 100, 1, 248},
+		{"garchomp 0 spe negativa", 102, 31, 0, 100, 0.9, 216},
+		{"nivel 50", 102, 31, 252, 50, 1.1, 169},
+		{"random battle nivel 84", 102, 31, RandomBattleEV, 84, 1, 220},
+	}
+	for _, tt := range tests {
+		if got := Stat(tt.base, tt.iv, tt.ev, tt.level, tt.nature); got != tt.want {
+			t.Errorf("%s: Stat = %d, se esperaba %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHP(t *testing.T) {
+	tests := []struct {
+		name                string
+		base, iv, ev, level int
+		want                int
+	}{
+		{"garchomp 0 hp", 108, 31, 0, 100, 357},
+		{"heatran 0 hp", 91, 31, 0, 100, 323},
+		{"blissey 252 hp", 255, 31, 252, 100, 714},
+		{"nivel 50", 108, 31, 252, 50, 215},
+		{"shedinja", 1, 31, 252, 100, 1},
+	}
+	for _, tt := range tests {
+		if got := HP(tt.base, tt.iv, tt.ev, tt.level); got != tt.want {
+			t.Errorf("%s: HP = %d, se esperaba %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNatureMultiplier(t *testing.T) {
+	tests := []struct {
+		nature, stat string
+		want         float64
+	}{
+		{"Jolly", "spe", 1.1},
+		{"Jolly", "spa", 0.9},
+		{"Jolly", "atk", 1},
+		{"Hardy", "atk", 1},
+		{"Quiet", "spe", 0.9},
+		{"", "hp", 1},
+	}
+	for _, tt := range tests {
+		if got := NatureMultiplier(tt.nature, tt.stat); got != tt.want {
+			t.Errorf("NatureMultiplier(%q, %q) = %v, se esperaba %v", tt.nature, tt.stat, got, tt.want)
+		}
+	}
+}
+
+func TestBoostMultiplier(t *testing.T) {
+	tests := map[int]float64{
+		0: 1, 1: 1.5, 2: 2, 6: 4, 7: 4,
+		-1: 2.0 / 3, -2: 0.5, -6: 0.25, -8: 0.25,
+	}
+	for stage, want := range tests {
+		if got := BoostMultiplier(stage); got != want {
+			t.Errorf("BoostMultiplier(%d) = %v, se esperaba %v", stage, got, want)
+		}
+	}
+}
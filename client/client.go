@@ -9,16 +9,36 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// Servidores públicos de Showdown.
 const (
-	showdownServerURL = "wss://sim3.psim.us/showdown/websocket"
+	DefaultServerURL      = "wss://sim3.psim.us/showdown/websocket"
+	DefaultLoginServerURL = "https://play.pokemonshowdown.com/~~showdown/action.php"
 )
 
+// Config indica a qué servidor conectarse.
+type Config struct {
+	// ServerURL es el websocket del servidor. Se puede apuntar a un
+	// servidor local para probar bots.
+	ServerURL string
+	// LoginServerURL es el action.php que firma las aserciones de login.
+	// Vacío se loguea sin aserción, lo que sólo aceptan servidores locales
+	// con noguestsecurity.
+	LoginServerURL string
+}
+
+// DefaultConfig apunta a los servidores públicos.
+func DefaultConfig() Config {
+	return Config{ServerURL: DefaultServerURL, LoginServerURL: DefaultLoginServerURL}
+}
+
 type ShowdownClient struct {
 	Conn *websocket.Conn
+
+	loginServerURL string
 }
 
-func NewShowdownClient() (*ShowdownClient, error) {
-	u, err := url.Parse(showdownServerURL)
+func NewShowdownClient(cfg Config) (*ShowdownClient, error) {
+	u, err := url.Parse(cfg.ServerURL)
 	if err != nil {
 		return nil, fmt.Errorf("error al parsear la url del server: %w", err)
 	}
@@ -37,7 +57,7 @@ func NewShowdownClient() (*ShowdownClient, error) {
 		return nil, fmt.Errorf("error al conectar con el websocket: %w", err)
 	}
 
-	client := &ShowdownClient{Conn: c}
+	client := &ShowdownClient{Conn: c, loginServerURL: cfg.LoginServerURL}
 	log.Println("conectado exitosamente al servidor de showdown.")
 
 	c.SetPingHandler(func(appData string) error {
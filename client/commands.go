@@ -0,0 +1,128 @@
+package client
+
+import (
+	"fmt"
+	"showdown-analizer/data"
+	"strings"
+)
+
+// Comandos globales y de sala de Showdown. Las respuestas llegan como
+// eventos (ver ParseEvent): |updatesearch|, |updatechallenges| o |popup|.
+
+// UploadTeam sube el equipo empaquetado que se usará en la próxima
+// búsqueda o desafío.
+func (sc *ShowdownClient) UploadTeam(packed string) error {
+	return sc.Send("|/utm " + packed)
+}
+
+func (sc *ShowdownClient) Search(format string) error {
+	return sc.Send("|/search " + format)
+}
+
+func (sc *ShowdownClient) CancelSearch() error {
+	return sc.Send("|/cancelsearch")
+}
+
+func (sc *ShowdownClient) Challenge(user, format string) error {
+	return sc.Send(fmt.Sprintf("|/challenge %s, %s", user, format))
+}
+
+func (sc *ShowdownClient) Accept(user string) error {
+	return sc.Send("|/accept " + user)
+}
+
+func (sc *ShowdownClient) Reject(user string) error {
+	return sc.Send("|/reject " + user)
+}
+
+func (sc *ShowdownClient) LeaveRoom(roomID string) error {
+	return sc.Send("|/leave " + roomID)
+}
+
+func (sc *ShowdownClient) Forfeit(roomID string) error {
+	return sc.Send(roomID + "|/forfeit")
+}
+
+func (sc *ShowdownClient) Timer(roomID string, on bool) error {
+	state := "off"
+	if on {
+		state = "on"
+	}
+	return sc.Send(fmt.Sprintf("%s|/timer %s", roomID, state))
+}
+
+// Choose responde al |request| rqid con choice ("move 1", "switch 3",
+// "team 2" o "default").
+func (sc *ShowdownClient) Choose(roomID, choice string, rqid int) error {
+	return sc.Send(fmt.Sprintf("%s|/choose %s|%d", roomID, choice, rqid))
+}
+
+// RequestRoomList pide las batallas en curso de format (vacío para todas)
+// con rating de al menos minElo. La respuesta llega como RoomList.
+func (sc *ShowdownClient) RequestRoomList(format string, minElo int) error {
+	elo := ""
+	if minElo > 0 {
+		elo = fmt.Sprint(minElo)
+	}
+	return sc.Send(fmt.Sprintf("|/cmd roomlist %s,%s", format, elo))
+}
+
+// RoomList pide la lista de batallas y espera la respuesta. Consume los
+// mensajes que lleguen mientras tanto, así que conviene usarlo en una
+// conexión dedicada.
+func (sc *ShowdownClient) RoomList(format string, minElo int) (RoomList, error) {
+	if err := sc.RequestRoomList(format, minElo); err != nil {
+		return RoomList{}, err
+	}
+	var list RoomList
+	var parseErr error
+	_, err := sc.waitFor(func(line string) (string, bool) {
+		ev, ok, err := ParseEvent(line)
+		if rl, isList := ev.(RoomList); ok && isList {
+			list = rl
+			return "", true
+		}
+		if err != nil && strings.HasPrefix(line, "|queryresponse|roomlist|") {
+			parseErr = err
+			return "", true
+		}
+		return "", false
+	})
+	if err != nil {
+		return RoomList{}, fmt.Errorf("error esperando roomlist: %w", err)
+	}
+	return list, parseErr
+}
+
+func (sc *ShowdownClient) RequestUserDetails(user string) error {
+	return sc.Send("|/cmd userdetails " + user)
+}
+
+// UserDetails pide los datos de user y espera la respuesta. Igual que
+// RoomList, consume los demás mensajes de la conexión.
+func (sc *ShowdownClient) UserDetails(user string) (UserDetails, error) {
+	if err := sc.RequestUserDetails(user); err != nil {
+		return UserDetails{}, err
+	}
+	var details UserDetails
+	var parseErr error
+	_, err := sc.waitFor(func(line string) (string, bool) {
+		if !strings.HasPrefix(line, "|queryresponse|userdetails|") {
+			return "", false
+		}
+		ev, _, err := ParseEvent(line)
+		if err != nil {
+			parseErr = err
+			return "", true
+		}
+		if ud := ev.(UserDetails); ud.UserID == data.ToID(user) {
+			details = ud
+			return "", true
+		}
+		return "", false
+	})
+	if err != nil {
+		return UserDetails{}, fmt.Errorf("error esperando userdetails: %w", err)
+	}
+	return details, parseErr
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Event es una respuesta global del servidor ya decodificada.
+type Event interface {
+	eventName() string
+}
+
+// SearchUpdate es |updatesearch|: los formatos en los que se está buscando
+// y las partidas en curso (room → título).
+type SearchUpdate struct {
+	Searching []string          `json:"searching"`
+	Games     map[string]string `json:"games"`
+}
+
+// ChallengeTo es el desafío enviado que todavía no se respondió.
+type ChallengeTo struct {
+	To     string `json:"to"`
+	Format string `json:"format"`
+}
+
+// ChallengesUpdate es |updatechallenges|: desafíos recibidos (usuario →
+// formato) y el enviado, si hay.
+type ChallengesUpdate struct {
+	ChallengesFrom map[string]string `json:"challengesFrom"`
+	ChallengeTo    *ChallengeTo      `json:"challengeTo"`
+}
+
+// RoomListEntry es una batalla de |queryresponse|roomlist|. MinElo es 0
+// cuando la sala no tiene rating (por ejemplo partidas de torneo).
+type RoomListEntry struct {
+	P1     string `json:"p1"`
+	P2     string `json:"p2"`
+	MinElo int    `json:"-"`
+}
+
+func (e *RoomListEntry) UnmarshalJSON(raw []byte) error {
+	var entry struct {
+		P1     string          `json:"p1"`
+		P2     string          `json:"p2"`
+		MinElo json.RawMessage `json:"minElo"`
+	}
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return err
+	}
+	e.P1, e.P2 = entry.P1, entry.P2
+	// minElo puede venir como número o como texto ("tour").
+	json.Unmarshal(entry.MinElo, &e.MinElo)
+	return nil
+}
+
+// RoomList es la respuesta a /cmd roomlist: sala → jugadores y rating.
+type RoomList struct {
+	Rooms map[string]RoomListEntry `json:"rooms"`
+}
+
+// UserDetails es la respuesta a /cmd userdetails. Rooms es nil si el
+// usuario no está conectado; las claves llevan delante el rango del usuario
+// en la sala y "☆" en las batallas que juega.
+type UserDetails struct {
+	UserID string                     `json:"userid"`
+	Name   string                     `json:"name"`
+	Rooms  map[string]json.RawMessage `json:"-"`
+}
+
+func (u *UserDetails) UnmarshalJSON(raw []byte) error {
+	var details struct {
+		UserID string          `json:"userid"`
+		Name   string          `json:"name"`
+		Rooms  json.RawMessage `json:"rooms"`
+	}
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return err
+	}
+	u.UserID, u.Name = details.UserID, details.Name
+	// Para usuarios desconectados rooms viene como false.
+	json.Unmarshal(details.Rooms, &u.Rooms)
+	return nil
+}
+
+// Battles devuelve las salas de batalla en las que el usuario es jugador.
+func (u UserDetails) Battles() []string {
+	var battles []string
+	for room := range u.Rooms {
+		if id, ok := strings.CutPrefix(room, "☆"); ok && strings.HasPrefix(id, "battle-") {
+			battles = append(battles, id)
+		}
+	}
+	sort.Strings(battles)
+	return battles
+}
+
+// Popup es un mensaje |popup|; Showdown usa "||" como salto de línea.
+type Popup struct {
+	Message string
+}
+
+func (SearchUpdate) eventName() string     { return "updatesearch" }
+func (ChallengesUpdate) eventName() string { return "updatechallenges" }
+func (Popup) eventName() string            { return "popup" }
+func (RoomList) eventName() string         { return "roomlist" }
+func (UserDetails) eventName() string      { return "userdetails" }
+
+// ParseEvent decodifica una línea global. ok es false si la línea no es un
+// evento conocido.
+func ParseEvent(line string) (Event, bool, error) {
+	kind, rest, found := strings.Cut(strings.TrimPrefix(line, "|"), "|")
+	if !found || !strings.HasPrefix(line, "|") {
+		return nil, false, nil
+	}
+	switch kind {
+	case "updatesearch":
+		var ev SearchUpdate
+		if err := json.Unmarshal([]byte(rest), &ev); err != nil {
+			return nil, true, fmt.Errorf("|updatesearch| inválido: %w", err)
+		}
+		return ev, true, nil
+	case "updatechallenges":
+		var ev ChallengesUpdate
+		if err := json.Unmarshal([]byte(rest), &ev); err != nil {
+			return nil, true, fmt.Errorf("|updatechallenges| inválido: %w", err)
+		}
+		return ev, true, nil
+	case "queryresponse":
+		query, body, _ := strings.Cut(rest, "|")
+		switch query {
+		case "roomlist":
+			var ev RoomList
+			if err := json.Unmarshal([]byte(body), &ev); err != nil {
+				return nil, true, fmt.Errorf("|queryresponse|roomlist| inválido: %w", err)
+			}
+			return ev, true, nil
+		case "userdetails":
+			var ev UserDetails
+			if err := json.Unmarshal([]byte(body), &ev); err != nil {
+				return nil, true, fmt.Errorf("|queryresponse|userdetails| inválido: %w", err)
+			}
+			return ev, true, nil
+		}
+		return nil, false, nil
+	case "popup":
+		return Popup{Message: strings.ReplaceAll(rest, "||", "\n")}, true, nil
+	}
+	return nil, false, nil
+}
+
+// ParseMessage separa un mensaje del servidor en la sala (vacía para los
+// globales) y sus líneas.
+func ParseMessage(message string) (string, []string) {
+	lines := strings.Split(message, "\n")
+	if strings.HasPrefix(lines[0], ">") {
+		return strings.TrimPrefix(lines[0], ">"), lines[1:]
+	}
+	return "", lines
+}
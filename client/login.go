@@ -0,0 +1,116 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"showdown-analizer/data"
+	"strings"
+	"time"
+)
+
+const loginTimeout = 15 * time.Second
+
+// Login identifica la conexión como username. Debe llamarse antes de leer
+// mensajes por otro lado, porque consume todo hasta recibir |challstr| y la
+// confirmación |updateuser|. Sin contraseña sólo funciona con nombres no
+// registrados.
+func (sc *ShowdownClient) Login(username, password string) error {
+	challstr, err := sc.waitFor(func(line string) (string, bool) {
+		return strings.CutPrefix(line, "|challstr|")
+	})
+	if err != nil {
+		return fmt.Errorf("error esperando challstr: %w", err)
+	}
+
+	assertion := ""
+	if sc.loginServerURL != "" {
+		assertion, err = getAssertion(sc.loginServerURL, username, password, challstr)
+		if err != nil {
+			return err
+		}
+	}
+	if err := sc.Send(fmt.Sprintf("|/trn %s,0,%s", username, assertion)); err != nil {
+		return err
+	}
+
+	_, err = sc.waitFor(func(line string) (string, bool) {
+		rest, ok := strings.CutPrefix(line, "|updateuser|")
+		if !ok {
+			return "", false
+		}
+		fields := strings.Split(rest, "|")
+		return rest, len(fields) >= 2 && fields[1] == "1"
+	})
+	if err != nil {
+		return fmt.Errorf("error esperando confirmación de login: %w", err)
+	}
+	log.Printf("logueado en showdown como %s", username)
+	return nil
+}
+
+func (sc *ShowdownClient) waitFor(match func(line string) (string, bool)) (string, error) {
+	sc.Conn.SetReadDeadline(time.Now().Add(loginTimeout))
+	defer sc.Conn.SetReadDeadline(time.Time{})
+	for {
+		_, message, err := sc.Conn.ReadMessage()
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(string(message), "\n") {
+			if strings.HasPrefix(line, "|popup|") {
+				log.Printf("popup de showdown: %s", line)
+			}
+			if value, ok := match(line); ok {
+				return value, nil
+			}
+		}
+	}
+}
+
+func getAssertion(loginServerURL, username, password, challstr string) (string, error) {
+	form := url.Values{"challstr": {challstr}}
+	if password == "" {
+		form.Set("act", "getassertion")
+		form.Set("userid", data.ToID(username))
+	} else {
+		form.Set("act", "login")
+		form.Set("name", username)
+		form.Set("pass", password)
+	}
+
+	httpClient := &http.Client{Timeout: loginTimeout}
+	resp, err := httpClient.PostForm(loginServerURL, form)
+	if err != nil {
+		return "", fmt.Errorf("error al contactar el login server: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error al leer respuesta del login server: %w", err)
+	}
+
+	if password == "" {
+		assertion := strings.TrimSpace(string(body))
+		if assertion == "" || strings.HasPrefix(assertion, ";") {
+			return "", fmt.Errorf("el nombre %s requiere contraseña", username)
+		}
+		return assertion, nil
+	}
+
+	// La respuesta de act=login viene prefijada con "]" para evitar XSSI.
+	var result struct {
+		ActionSuccess bool   `json:"actionsuccess"`
+		Assertion     string `json:"assertion"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(string(body), "]")), &result); err != nil {
+		return "", fmt.Errorf("respuesta inválida del login server: %w", err)
+	}
+	if !result.ActionSuccess || result.Assertion == "" || strings.HasPrefix(result.Assertion, ";") {
+		return "", fmt.Errorf("login rechazado para %s", username)
+	}
+	return result.Assertion, nil
+}
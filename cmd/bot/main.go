@@ -0,0 +1,70 @@
+// Command bot juega partidas en Showdown eligiendo con el motor de
+// sugerencias. Pensado para un servidor local: por ejemplo
+//
+//	go run ./cmd/bot -server ws://localhost:8000/showdown/websocket -login-server "" -user bot1 -ladder -games 10
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"showdown-analizer/bot"
+	"showdown-analizer/client"
+	"showdown-analizer/data"
+	"showdown-analizer/search"
+	"strings"
+)
+
+func main() {
+	serverURL := flag.String("server", client.DefaultServerURL, "websocket del servidor de Showdown")
+	loginURL := flag.String("login-server", client.DefaultLoginServerURL, "action.php para firmar el login (vacío para servidores locales)")
+	user := flag.String("user", os.Getenv("SHOWDOWN_USERNAME"), "usuario del bot")
+	format := flag.String("format", "gen9randombattle", "formato en el que jugar")
+	games := flag.Int("games", 1, "partidas a jugar (0 sin límite)")
+	ladder := flag.Bool("ladder", false, "buscar partida en el ladder en vez de esperar desafíos")
+	accept := flag.String("accept", "", "aceptar sólo desafíos de este usuario")
+	teamFile := flag.String("team", "", "archivo con el equipo empaquetado para formatos sin equipo aleatorio")
+	budget := flag.Duration("budget", search.DefaultOptions.Budget, "tiempo de búsqueda por decisión")
+	dataDir := flag.String("data-dir", os.Getenv("SHOWDOWN_DATA_DIR"), "directorio con archivos de datos que reemplazan a los embebidos")
+	flag.Parse()
+
+	if *user == "" {
+		log.Fatal("Falta -user o SHOWDOWN_USERNAME")
+	}
+	if _, err := data.Load(*dataDir); err != nil {
+		log.Fatalf("Error cargando datos del juego: %v", err)
+	}
+
+	cfg := bot.Config{
+		Format: *format,
+		Games:  *games,
+		Ladder: *ladder,
+		Accept: *accept,
+		Search: search.DefaultOptions,
+		Dex:    data.Current(),
+	}
+	cfg.Search.Budget = *budget
+	if *teamFile != "" {
+		raw, err := os.ReadFile(*teamFile)
+		if err != nil {
+			log.Fatalf("Error leyendo el equipo: %v", err)
+		}
+		cfg.Team = strings.TrimSpace(string(raw))
+	}
+
+	sc, err := client.NewShowdownClient(client.Config{ServerURL: *serverURL, LoginServerURL: *loginURL})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := sc.Login(*user, os.Getenv("SHOWDOWN_PASSWORD")); err != nil {
+		log.Fatalf("Error al loguearse como %s: %v", *user, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := bot.New(sc, cfg).Run(ctx); err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
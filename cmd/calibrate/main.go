@@ -0,0 +1,114 @@
+// Command calibrate ajusta los pesos de la probabilidad de victoria con un
+// corpus de batallas terminadas. Lee logs de protocolo (.log) o replays
+// exportados de Showdown (.json con el campo "log") y escribe los pesos en
+// JSON para pasarlos al servidor con -winprob-weights.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/parser"
+	"showdown-analizer/replay"
+	"showdown-analizer/winprob"
+	"strings"
+)
+
+func main() {
+	dir := flag.String("dir", "replays", "directorio con los logs de batallas terminadas")
+	out := flag.String("out", "winprob.json", "archivo donde escribir los pesos ajustados")
+	dataDir := flag.String("data-dir", os.Getenv("SHOWDOWN_DATA_DIR"), "directorio con archivos de datos que reemplazan a los embebidos")
+	epochs := flag.Int("epochs", 2000, "iteraciones de descenso de gradiente")
+	rate := flag.Float64("rate", 0.5, "tasa de aprendizaje")
+	flag.Parse()
+
+	if _, err := data.Load(*dataDir); err != nil {
+		log.Fatalf("Error cargando datos del juego: %v", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(*dir, "*"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var samples []winprob.Sample
+	battles := 0
+	for _, path := range paths {
+		r, err := replay.ReadFile(path)
+		if errors.Is(err, replay.ErrUnsupported) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Se ignora %s: %v", path, err)
+			continue
+		}
+		s := samplesFromLog(r.Log)
+		if len(s) == 0 {
+			log.Printf("Se ignora %s: no terminó o no tiene turnos", path)
+			continue
+		}
+		samples = append(samples, s...)
+		battles++
+	}
+	if len(samples) == 0 {
+		log.Fatalf("No hay batallas terminadas en %s", *dir)
+	}
+
+	weights := winprob.Fit(samples, *epochs, *rate)
+	buckets, brier := winprob.Calibration(&weights, samples, 10)
+	_, baseline := winprob.Calibration(&winprob.DefaultWeights, samples, 10)
+
+	fmt.Printf("%d batallas, %d turnos\n", battles, len(samples))
+	fmt.Printf("Brier: %.4f (pesos por defecto: %.4f)\n", brier, baseline)
+	fmt.Println("Predicho   Observado  Turnos")
+	for _, b := range buckets {
+		if b.Count > 0 {
+			fmt.Printf("%5.1f%%    %5.1f%%     %d\n", b.Predicted*100, b.Observed*100, b.Count)
+		}
+	}
+
+	raw, err := json.MarshalIndent(weights, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, raw, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Pesos escritos en %s\n", *out)
+}
+
+// samplesFromLog reproduce el log y toma las features al empezar cada
+// turno, etiquetadas con el ganador.
+func samplesFromLog(text string) []winprob.Sample {
+	lines := strings.Split(text, "\n")
+	format := ""
+	for _, line := range lines {
+		if strings.HasPrefix(line, "|tier|") {
+			format = data.ToID(strings.TrimPrefix(line, "|tier|"))
+			break
+		}
+	}
+	dex := data.Current().ForGen(data.GenFromFormat(format))
+
+	state := game.NewBattleState()
+	var features [][]float64
+	for _, line := range lines {
+		parser.ProcessLine(dex, state, line)
+		if strings.HasPrefix(line, "|turn|") {
+			features = append(features, winprob.Features(dex, state))
+		}
+	}
+	if state.Winner == "" {
+		return nil
+	}
+	samples := make([]winprob.Sample, len(features))
+	for i, f := range features {
+		samples[i] = winprob.Sample{Features: f, P1Won: state.Winner == "p1"}
+	}
+	return samples
+}
@@ -0,0 +1,75 @@
+// Command replay analiza offline una batalla terminada: recibe el .log o
+// el .json de replay.pokemonshowdown.com y escribe un reporte turno por
+// turno con los mismos resúmenes que la vista en vivo.
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"showdown-analizer/data"
+	"showdown-analizer/replay"
+	"showdown-analizer/search"
+)
+
+//go:embed report.html
+var reportHTML string
+
+var page = template.Must(template.New("report").Parse(reportHTML))
+
+func main() {
+	perspective := flag.String("perspective", "", "analizar como p1 o p2 (vacío para espectador)")
+	asJSON := flag.Bool("json", false, "escribir el reporte en JSON en vez de HTML")
+	out := flag.String("out", "", "archivo de salida (vacío para stdout)")
+	dataDir := flag.String("data-dir", os.Getenv("SHOWDOWN_DATA_DIR"), "directorio con archivos de datos que reemplazan a los embebidos")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "uso: %s [flags] replay.log|replay.json\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *perspective != "" && *perspective != "p1" && *perspective != "p2" {
+		log.Fatalf("Perspectiva inválida: %s", *perspective)
+	}
+
+	if _, err := data.Load(*dataDir); err != nil {
+		log.Fatalf("Error cargando datos del juego: %v", err)
+	}
+	r, err := replay.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error leyendo %s: %v", flag.Arg(0), err)
+	}
+	// Los logs del parser tapan el reporte.
+	log.SetOutput(io.Discard)
+	report := replay.Analyze(context.Background(), data.Current(), r, *perspective, search.DefaultOptions)
+	log.SetOutput(os.Stderr)
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = page.Execute(w, report)
+	}
+	if err != nil {
+		log.Fatalf("Error escribiendo el reporte: %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+// Command simcheck compara el simulador con batallas reales: por cada golpe
+// sin crítico de los logs calcula el rango de daño que da sim y cuenta
+// cuántos golpes observados caen dentro, y reproduce cada turno con
+// sim.Step para ver si las acciones salen en el mismo orden que en el log.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/parser"
+	"showdown-analizer/replay"
+	"showdown-analizer/sim"
+	"slices"
+	"strings"
+)
+
+// result acumula lo comparado en uno o varios logs.
+type result struct {
+	hits, hitsInside     int
+	turns, turnsMatching int
+	misses               []string
+}
+
+type pendingHit struct {
+	attacker, target *game.Pokemon
+	move             string
+	lo, hi           int
+	hpBefore         int
+	crit             bool
+}
+
+func main() {
+	dir := flag.String("dir", "replays", "directorio con los logs de batallas")
+	dataDir := flag.String("data-dir", os.Getenv("SHOWDOWN_DATA_DIR"), "directorio con archivos de datos que reemplazan a los embebidos")
+	verbose := flag.Bool("v", false, "listar los golpes fuera de rango")
+	flag.Parse()
+
+	if _, err := data.Load(*dataDir); err != nil {
+		log.Fatalf("Error cargando datos del juego: %v", err)
+	}
+	// Los logs del parser tapan el reporte.
+	log.SetOutput(io.Discard)
+
+	paths, _ := filepath.Glob(filepath.Join(*dir, "*"))
+	var total result
+	for _, path := range paths {
+		r, err := replay.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		res := check(r.Log)
+		total.hits += res.hits
+		total.hitsInside += res.hitsInside
+		total.turns += res.turns
+		total.turnsMatching += res.turnsMatching
+		if *verbose {
+			for _, m := range res.misses {
+				fmt.Printf("%s: %s\n", filepath.Base(path), m)
+			}
+		}
+	}
+	if total.hits == 0 {
+		fmt.Println("No se encontraron golpes comparables")
+	} else {
+		fmt.Printf("%d/%d golpes dentro del rango simulado (%.1f%%)\n", total.hitsInside, total.hits, float64(total.hitsInside)*100/float64(total.hits))
+	}
+	if total.turns > 0 {
+		fmt.Printf("%d/%d turnos con el mismo orden de acciones que sim.Step (%.1f%%)\n", total.turnsMatching, total.turns, float64(total.turnsMatching)*100/float64(total.turns))
+	}
+}
+
+// check reproduce el log y, en cada |move| seguido de |-damage| directo,
+// compara la vida perdida con sim.DamageRange calculado antes del golpe.
+// Además compara cada turno terminado con checkTurn.
+func check(text string) result {
+	lines := strings.Split(text, "\n")
+	format := ""
+	for _, line := range lines {
+		if strings.HasPrefix(line, "|tier|") {
+			format = data.ToID(strings.TrimPrefix(line, "|tier|"))
+			break
+		}
+	}
+	dex := data.Current().ForGen(data.GenFromFormat(format))
+	state := game.NewBattleState()
+
+	var res result
+	var pending *pendingHit
+	var turnStart *game.BattleState
+	var turnLines []string
+	for _, line := range lines {
+		parts := strings.Split(strings.TrimSpace(line), "|")
+		if len(parts) < 2 {
+			continue
+		}
+		turnLines = append(turnLines, strings.TrimSpace(line))
+		switch parts[1] {
+		case "turn":
+			parser.ProcessLine(dex, state, line)
+			if turnStart != nil {
+				if matching, ok := checkTurn(dex, turnStart, turnLines); ok {
+					res.turns++
+					if matching {
+						res.turnsMatching++
+					} else {
+						res.misses = append(res.misses, fmt.Sprintf("turno %d: orden distinto al simulado", turnStart.Turn))
+					}
+				}
+			}
+			turnStart, turnLines = state.Snapshot(), nil
+			continue
+		case "move":
+			pending = nil
+			parser.ProcessLine(dex, state, line)
+			if strings.Contains(line, "[from]") || len(state.TurnMoves) == 0 {
+				continue
+			}
+			event := state.TurnMoves[len(state.TurnMoves)-1]
+			if event.Target == nil || event.Target == event.Pokemon {
+				continue
+			}
+			lo, hi, ok := sim.DamageRange(dex, state, event.Pokemon, event.Target, event.Move)
+			if ok {
+				pending = &pendingHit{attacker: event.Pokemon, target: event.Target, move: event.Move, lo: lo, hi: hi, hpBefore: event.Target.HP}
+			}
+			continue
+		case "-crit":
+			if pending != nil {
+				pending.crit = true
+			}
+		case "-damage":
+			if pending != nil && !pending.crit && len(parts) == 4 {
+				before := pending.hpBefore
+				parser.ProcessLine(dex, state, line)
+				lost := before - pending.target.HP
+				// Un golpe que debilita sólo dice que el daño alcanzó la vida restante.
+				ok := (lost >= pending.lo && lost <= pending.hi) || (pending.target.HP == 0 && pending.hi >= before)
+				res.hits++
+				if ok {
+					res.hitsInside++
+				} else {
+					res.misses = append(res.misses, fmt.Sprintf("%s -> %s con %s: perdió %d, rango %d–%d",
+						pending.attacker.Name, pending.target.Name, pending.move, lost, pending.lo, pending.hi))
+				}
+				pending = nil
+				continue
+			}
+		}
+		parser.ProcessLine(dex, state, line)
+	}
+	return res
+}
+
+// checkTurn aplica con sim.Step las elecciones reales del turno que empezó
+// en start y compara qué acciones elegidas (|move| y |switch|) salen y en
+// qué orden. ok es false si falta la elección de algún jugador, por ejemplo
+// por |cant|.
+func checkTurn(dex *data.Dex, start *game.BattleState, lines []string) (matching, ok bool) {
+	actual := replay.ActualChoices(lines)
+	var choices [2]sim.Choice
+	for i, id := range []string{"p1", "p2"} {
+		c, found := actual[id]
+		if !found {
+			return false, false
+		}
+		if c.Switch {
+			choices[i].Switch = c.Name
+		} else {
+			choices[i].Move = c.Name
+		}
+	}
+	_, simulated := sim.Step(dex, start, choices, sim.NewPRNG([4]uint16{1, 2, 3, 4}))
+	return slices.Equal(chosenActions(simulated), chosenActions(lines)), true
+}
+
+// chosenActions lista los |move| y |switch| anteriores a |upkeep|, sin los
+// movimientos llamados por otros ni los cambios por debilitamiento.
+func chosenActions(lines []string) []string {
+	var actions []string
+	for _, line := range lines {
+		if line == "|upkeep" {
+			break
+		}
+		parts := strings.Split(line, "|")
+		if len(parts) < 4 || strings.Contains(line, "[from]") {
+			continue
+		}
+		switch parts[1] {
+		case "move":
+			actions = append(actions, "move "+parts[2]+" "+parts[3])
+		case "switch":
+			// Los detalles cambian entre el log y sim (género, forma).
+			actions = append(actions, "switch "+parts[2])
+		}
+	}
+	return actions
+}
@@ -2,103 +2,299 @@ package data
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"strings"
 )
 
 type PokemonData struct {
-	Name  string   `json:"name"`
-	Types []string `json:"types"`
+	Name      string            `json:"name"`
+	Types     []string          `json:"types"`
+	Abilities map[string]string `json:"abilities"`
+	BaseStats map[string]int    `json:"baseStats"`
 }
 
 type MoveData struct {
-	Name  string `json:"name"`
-	Type  string `json:"type"`
-	Power int    `json:"power"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Power    int    `json:"power"`
+	Priority int    `json:"priority"`
+	Category string `json:"category"`
+	MaxPP    int    `json:"maxpp"`
+	Heal     bool   `json:"heal"`
+	Contact  bool   `json:"contact"`
+	Sound    bool   `json:"sound"`
+
+	// Efectos que usa el simulador. Accuracy 0 es un movimiento que no falla.
+	Accuracy      int             `json:"accuracy"`
+	Target        string          `json:"target"`
+	Status        string          `json:"status"`
+	Weather       string          `json:"weather"`
+	SideCondition string          `json:"sideCondition"`
+	Boosts        map[string]int  `json:"boosts"`
+	SelfBoosts    map[string]int  `json:"selfBoosts"`
+	Drain         [2]int          `json:"drain"`
+	Recoil        [2]int          `json:"recoil"`
+	HealFraction  [2]int          `json:"healFraction"`
+	CritRatio     int             `json:"critRatio"`
+	SelfSwitch    bool            `json:"selfSwitch"`
+	Secondaries   []MoveSecondary `json:"secondaries"`
+}
+
+// MoveSecondary es un efecto con probabilidad de un movimiento de daño.
+type MoveSecondary struct {
+	Chance     int            `json:"chance"`
+	Status     string         `json:"status"`
+	Boosts     map[string]int `json:"boosts"`
+	SelfBoosts map[string]int `json:"selfBoosts"`
+	Flinch     bool           `json:"flinch"`
 }
 
 type RawPokemonData struct {
-	Name  string   `json:"name"`
-	Types []string `json:"types"`
+	Name      string            `json:"name"`
+	Types     []string          `json:"types"`
+	Abilities map[string]string `json:"abilities"`
+	BaseStats map[string]int    `json:"baseStats"`
 }
 
 type RawMoveData struct {
-	Name  string `json:"name"`
-	Type  string `json:"type"`
-	Power int    `json:"basePower"`
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Power      int            `json:"basePower"`
+	Priority   int            `json:"priority"`
+	Category   string         `json:"category"`
+	PP         int            `json:"pp"`
+	NoPPBoosts bool           `json:"noPPBoosts"`
+	Flags      map[string]int `json:"flags"`
+
+	// accuracy es un número o true para los que no fallan.
+	Accuracy      json.RawMessage    `json:"accuracy"`
+	Target        string             `json:"target"`
+	Status        string             `json:"status"`
+	Weather       string             `json:"weather"`
+	SideCondition string             `json:"sideCondition"`
+	Boosts        map[string]int     `json:"boosts"`
+	Self          *RawMoveSecondary  `json:"self"`
+	Drain         []int              `json:"drain"`
+	Recoil        []int              `json:"recoil"`
+	HealFraction  []int              `json:"heal"`
+	CritRatio     int                `json:"critRatio"`
+	SelfSwitch    json.RawMessage    `json:"selfSwitch"`
+	Secondary     *RawMoveSecondary  `json:"secondary"`
+	Secondaries   []RawMoveSecondary `json:"secondaries"`
 }
 
-var pokemonDB map[string]PokemonData
-var moveDB map[string]MoveData
+type RawMoveSecondary struct {
+	Chance         int               `json:"chance"`
+	Status         string            `json:"status"`
+	VolatileStatus string            `json:"volatileStatus"`
+	Boosts         map[string]int    `json:"boosts"`
+	Self           *RawMoveSecondary `json:"self"`
+}
 
-func LoadPokemonData(path string) error {
-	file, err := os.Open(path)
+func fraction(raw []int) [2]int {
+	if len(raw) != 2 {
+		return [2]int{}
+	}
+	return [2]int{raw[0], raw[1]}
+}
+
+func (s RawMoveSecondary) convert() MoveSecondary {
+	sec := MoveSecondary{
+		Chance: s.Chance,
+		Status: s.Status,
+		Boosts: s.Boosts,
+		Flinch: s.VolatileStatus == "flinch",
+	}
+	if s.Self != nil {
+		sec.SelfBoosts = s.Self.Boosts
+	}
+	return sec
+}
+
+func loadPokemonData(src dataSource, name string) (map[string]PokemonData, error) {
+	file, source, err := src.open(name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer file.Close()
 
 	var rawData map[string]RawPokemonData
 	if err := json.NewDecoder(file).Decode(&rawData); err != nil {
-		return err
+		return nil, fmt.Errorf("error al leer %s: %w", source, err)
 	}
 
-	pokemonDB = make(map[string]PokemonData)
+	pokemonDB := make(map[string]PokemonData)
 	for _, p := range rawData {
 		pokemonDB[strings.ToLower(p.Name)] = PokemonData{
-			Name:  p.Name,
-			Types: p.Types,
+			Name:      p.Name,
+			Types:     p.Types,
+			Abilities: p.Abilities,
+			BaseStats: p.BaseStats,
 		}
 	}
-	return nil
+	return pokemonDB, nil
 }
 
-func LoadMoveData(path string) error {
-	file, err := os.Open(path)
+func loadMoveData(src dataSource, name string) (map[string]MoveData, error) {
+	file, source, err := src.open(name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer file.Close()
 
 	var rawData map[string]RawMoveData
 	if err := json.NewDecoder(file).Decode(&rawData); err != nil {
-		return err
+		return nil, fmt.Errorf("error al leer %s: %w", source, err)
 	}
 
-	moveDB = make(map[string]MoveData)
+	moveDB := make(map[string]MoveData)
 	for _, m := range rawData {
-		moveDB[strings.ToLower(m.Name)] = MoveData{
-			Name:  m.Name,
-			Type:  m.Type,
-			Power: m.Power,
+		// Se asume que todo movimiento lleva PP Ups al máximo (+60%).
+		maxPP := m.PP
+		if !m.NoPPBoosts {
+			maxPP = m.PP * 8 / 5
 		}
+		move := MoveData{
+			Name:     m.Name,
+			Type:     m.Type,
+			Power:    m.Power,
+			Priority: m.Priority,
+			Category: m.Category,
+			MaxPP:    maxPP,
+			Heal:     m.Flags["heal"] == 1,
+			Contact:  m.Flags["contact"] == 1,
+			Sound:    m.Flags["sound"] == 1,
+
+			Target:        m.Target,
+			Status:        m.Status,
+			Weather:       m.Weather,
+			SideCondition: m.SideCondition,
+			Boosts:        m.Boosts,
+			Drain:         fraction(m.Drain),
+			Recoil:        fraction(m.Recoil),
+			HealFraction:  fraction(m.HealFraction),
+			CritRatio:     m.CritRatio,
+			SelfSwitch:    len(m.SelfSwitch) > 0 && string(m.SelfSwitch) != "false",
+		}
+		json.Unmarshal(m.Accuracy, &move.Accuracy)
+		if m.Self != nil {
+			move.SelfBoosts = m.Self.Boosts
+		}
+		if m.Secondary != nil {
+			move.Secondaries = append(move.Secondaries, m.Secondary.convert())
+		}
+		for _, sec := range m.Secondaries {
+			move.Secondaries = append(move.Secondaries, sec.convert())
+		}
+		moveDB[strings.ToLower(m.Name)] = move
 	}
-	return nil
+	return moveDB, nil
 }
 
-func GetPokemonTypes(name string) []string {
-	if p, ok := pokemonDB[strings.ToLower(name)]; ok {
+func (d *Dex) GetPokemonTypes(name string) []string {
+	if p, ok := d.pokemon[strings.ToLower(name)]; ok {
 		return p.Types
 	}
 	return nil
 }
 
-func GetMoveTypeAndPower(name string) (string, int, error) {
-	if m, ok := moveDB[strings.ToLower(name)]; ok {
+func (d *Dex) GetBaseStats(name string) map[string]int {
+	if p, ok := d.pokemon[strings.ToLower(name)]; ok {
+		return p.BaseStats
+	}
+	return nil
+}
+
+func (d *Dex) GetMove(name string) (MoveData, bool) {
+	m, ok := d.moves[strings.ToLower(name)]
+	return m, ok
+}
+
+func (d *Dex) GetPokemonAbilities(name string) []string {
+	p, ok := d.pokemon[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	abilities := make([]string, 0, len(p.Abilities))
+	for _, a := range p.Abilities {
+		abilities = append(abilities, a)
+	}
+	return abilities
+}
+
+func (d *Dex) GetMovePriority(name string) int {
+	if m, ok := d.moves[strings.ToLower(name)]; ok {
+		return m.Priority
+	}
+	return 0
+}
+
+func (d *Dex) GetMoveTypeAndPower(name string) (string, int, error) {
+	if m, ok := d.moves[strings.ToLower(name)]; ok {
 		return m.Type, m.Power, nil
 	}
 	return "", 80, fmt.Errorf("movimiento no encontrado: %s", name)
 }
 
-func GetAllMoves() []MoveData {
+// GetMoveName traduce un ID como los de las estadísticas de Smogon
+// ("uturn") al nombre que muestra Showdown ("U-turn").
+func (d *Dex) GetMoveName(id string) string {
+	if name, ok := d.moveNames[id]; ok {
+		return name
+	}
+	return id
+}
+
+func (d *Dex) GetAbilityName(id string) string {
+	if name, ok := d.abilityNames[id]; ok {
+		return name
+	}
+	return id
+}
+
+// GetItemName traduce el ID de un objeto ("choicescarf") a su nombre
+// ("Choice Scarf"). Si no lo conoce devuelve el ID.
+func (d *Dex) GetItemName(id string) string {
+	if name, ok := d.itemNames[ToID(id)]; ok {
+		return name
+	}
+	return id
+}
+
+// loadItemNames lee los nombres de objetos; el archivo es opcional.
+func loadItemNames(src dataSource, name string) (map[string]string, error) {
+	names := map[string]string{}
+	file, source, err := src.open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return names, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var raw map[string]struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(file).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("error al leer %s: %w", source, err)
+	}
+	for id, item := range raw {
+		names[ToID(id)] = item.Name
+	}
+	return names, nil
+}
+
+func (d *Dex) GetAllMoves() []MoveData {
 	var moves []MoveData
-	for _, move := range moveDB {
+	for _, move := range d.moves {
 		moves = append(moves, move)
 	}
 	return moves
 }
 
-func GetPokemonMovepool(pokemonName string) []MoveData {
-	return GetAllMoves()
+func (d *Dex) GetPokemonMovepool(pokemonName string) []MoveData {
+	return d.GetAllMoves()
 }
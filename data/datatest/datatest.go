@@ -0,0 +1,28 @@
+// Package datatest arma el Dex de los tests con los datos embebidos, sin
+// tocar el Dex global que carga data.Load.
+package datatest
+
+import (
+	"showdown-analizer/data"
+	"sync"
+	"testing"
+)
+
+var (
+	once sync.Once
+	dex  *data.Dex
+	err  error
+)
+
+// Dex devuelve el Dex embebido con la tabla de tipos de gen. Se construye
+// una sola vez por binario de test.
+func Dex(t testing.TB, gen int) *data.Dex {
+	t.Helper()
+	once.Do(func() {
+		dex, err = data.NewDex(data.Embedded())
+	})
+	if err != nil {
+		t.Fatalf("cargando datos embebidos: %v", err)
+	}
+	return dex.ForGen(gen)
+}
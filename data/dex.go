@@ -0,0 +1,121 @@
+package data
+
+import (
+	"io/fs"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// Dex agrupa todas las tablas de datos de una carga. Es inmutable una vez
+// construido, así que puede compartirse entre goroutines sin sincronización.
+type Dex struct {
+	Gen int
+
+	pokemon    map[string]PokemonData
+	moves      map[string]MoveData
+	typeCharts map[string]TypeChart
+	randomSets map[string]map[string]RandomBattleSpecies
+	usage      map[string][]*UsageStats
+	report     LoadReport
+
+	moveNames    map[string]string
+	abilityNames map[string]string
+	itemNames    map[string]string
+}
+
+// NewDex construye un Dex leyendo únicamente de fsys, sin caer en los datos
+// embebidos. Sirve para fixtures y datasets alternativos.
+func NewDex(fsys fs.FS) (*Dex, error) {
+	return buildDex(newDataSource(dataLayer{name: "fs", label: "fs", fsys: fsys}))
+}
+
+func buildDex(src dataSource) (*Dex, error) {
+	d := &Dex{}
+
+	var err error
+	if d.pokemon, err = loadPokemonData(src, "pokedex.json"); err != nil {
+		return d, err
+	}
+	if d.moves, err = loadMoveData(src, "moves.json"); err != nil {
+		return d, err
+	}
+	if d.typeCharts, err = loadTypeCharts(src); err != nil {
+		return d, err
+	}
+	if d.randomSets, err = loadRandomBattleSets(src); err != nil {
+		return d, err
+	}
+	if d.usage, err = loadUsageStats(src); err != nil {
+		return d, err
+	}
+	if d.itemNames, err = loadItemNames(src, "items.json"); err != nil {
+		return d, err
+	}
+	// Los sets de random battle nombran objetos que pueden faltar en
+	// items.json.
+	for _, species := range d.randomSets {
+		for _, entry := range species {
+			for _, set := range entry.Sets {
+				for _, item := range set.Items {
+					if _, ok := d.itemNames[ToID(item)]; !ok {
+						d.itemNames[ToID(item)] = item
+					}
+				}
+			}
+		}
+	}
+
+	d.moveNames = make(map[string]string, len(d.moves))
+	for _, m := range d.moves {
+		d.moveNames[ToID(m.Name)] = m.Name
+	}
+	d.abilityNames = make(map[string]string)
+	for _, p := range d.pokemon {
+		for _, a := range p.Abilities {
+			d.abilityNames[ToID(a)] = a
+		}
+	}
+
+	d.report.Version = readVersion(src)
+	d.report.Source = src.summary()
+	d.report.Files = maps.Clone(src.served)
+	d.report.Pokemon = len(d.pokemon)
+	d.report.Moves = len(d.moves)
+	d.report.TypeChart = len(d.GetTypeNames())
+	d.report.RandomFormats = len(d.randomSets)
+	d.report.UsageFormats = len(d.usage)
+	return d, nil
+}
+
+func (d *Dex) Report() LoadReport {
+	return d.report
+}
+
+// ForGen devuelve una vista del mismo Dex con la tabla de tipos de gen.
+func (d *Dex) ForGen(gen int) *Dex {
+	if gen == d.Gen {
+		return d
+	}
+	view := *d
+	view.Gen = gen
+	return &view
+}
+
+// GenFromFormat extrae la generación de un formato o sala, por ejemplo
+// "gen9randombattle" o "battle-gen4ou-123". Devuelve 0 si no la encuentra.
+func GenFromFormat(format string) int {
+	format = strings.TrimPrefix(strings.ToLower(format), "battle-")
+	if !strings.HasPrefix(format, "gen") {
+		return 0
+	}
+	end := 3
+	for end < len(format) && format[end] >= '0' && format[end] <= '9' {
+		end++
+	}
+	gen, err := strconv.Atoi(format[3:end])
+	if err != nil {
+		return 0
+	}
+	return gen
+}
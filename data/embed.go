@@ -0,0 +1,145 @@
+package data
+
+import (
+	"compress/gzip"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+)
+
+//go:embed VERSION pokedex.json.gz moves.json.gz typechart.json items.json mods/*/typechart.json random-battles
+var embedded embed.FS
+
+// Embedded devuelve los datos embebidos en el binario, para armar un Dex
+// con NewDex sin pasar por el Dex global.
+func Embedded() fs.FS {
+	return embedded
+}
+
+type LoadReport struct {
+	// Source resume de dónde salieron los datos; Files dice qué capa sirvió
+	// cada archivo.
+	Source        string
+	Files         map[string]string
+	Version       string
+	Pokemon       int
+	Moves         int
+	TypeChart     int
+	RandomFormats int
+	UsageFormats  int
+}
+
+func (r LoadReport) String() string {
+	return fmt.Sprintf("versión %s (%s): %d Pokémon, %d movimientos, %d tipos, %d formatos random, %d formatos con usage stats",
+		r.Version, r.Source, r.Pokemon, r.Moves, r.TypeChart, r.RandomFormats, r.UsageFormats)
+}
+
+type dataLayer struct {
+	name string
+	// label es cómo se nombra la capa en el reporte de carga.
+	label string
+	fsys  fs.FS
+}
+
+// dataSource busca cada archivo en sus capas en orden; la primera que lo
+// tenga (plano o .gz) gana. served anota qué capa sirvió cada archivo.
+type dataSource struct {
+	layers []dataLayer
+	served map[string]string
+}
+
+func newDataSource(layers ...dataLayer) dataSource {
+	return dataSource{layers: layers, served: map[string]string{}}
+}
+
+func sourceForDir(dir string) dataSource {
+	embeddedLayer := dataLayer{name: "embed", label: "embebidos", fsys: embedded}
+	if dir == "" {
+		return newDataSource(embeddedLayer)
+	}
+	return newDataSource(dataLayer{name: dir, label: "override " + dir, fsys: os.DirFS(dir)}, embeddedLayer)
+}
+
+// summary describe qué capas sirvieron los archivos leídos hasta ahora.
+func (src dataSource) summary() string {
+	counts := map[string]int{}
+	for _, label := range src.served {
+		counts[label]++
+	}
+	var parts []string
+	for _, layer := range src.layers {
+		if n := counts[layer.label]; n > 0 {
+			if len(counts) == 1 {
+				return layer.label
+			}
+			parts = append(parts, fmt.Sprintf("%s: %d de %d archivos", layer.label, n, len(src.served)))
+		}
+	}
+	if len(parts) == 0 {
+		return "sin archivos"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func readVersion(src dataSource) string {
+	rc, _, err := src.open("VERSION")
+	if err != nil {
+		return "desconocida"
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return "desconocida"
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func (src dataSource) open(name string) (io.ReadCloser, string, error) {
+	for i, layer := range src.layers {
+		rc, err := openFrom(layer.fsys, name)
+		if err == nil {
+			if i < len(src.layers)-1 {
+				log.Printf("[Data] usando %s desde %s", name, layer.name)
+			}
+			src.served[name] = layer.label
+			return rc, layer.name + ":" + name, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+	return nil, "", fmt.Errorf("error al abrir %s: %w", name, fs.ErrNotExist)
+}
+func openFrom(fsys fs.FS, name string) (io.ReadCloser, error) {
+	if f, err := fsys.Open(name); err == nil {
+		return f, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	f, err := fsys.Open(name + ".gz")
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error al descomprimir %s.gz: %w", name, err)
+	}
+	return &gzipFile{Reader: zr, file: f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	file fs.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
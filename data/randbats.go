@@ -0,0 +1,106 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+)
+
+type RandomBattleSet struct {
+	Role      string   `json:"role"`
+	Movepool  []string `json:"movepool"`
+	Abilities []string `json:"abilities"`
+	TeraTypes []string `json:"teraTypes"`
+	Items     []string `json:"items"`
+}
+
+type RandomBattleSpecies struct {
+	Level int               `json:"level"`
+	Sets  []RandomBattleSet `json:"sets"`
+}
+
+// Los data.json de gens anteriores a la 9 no tienen roles: cada especie
+// lista directamente moves, abilities e items. Se normalizan a un único set.
+type rawRandomBattleSpecies struct {
+	Level     int               `json:"level"`
+	Sets      []RandomBattleSet `json:"sets"`
+	Moves     []string          `json:"moves"`
+	Abilities []string          `json:"abilities"`
+	Items     []string          `json:"items"`
+}
+
+const maxRandomBattleGen = 9
+
+func randomBattleFormat(gen int) string {
+	return fmt.Sprintf("gen%drandombattle", gen)
+}
+
+func loadRandomBattleSets(src dataSource) (map[string]map[string]RandomBattleSpecies, error) {
+	formats := make(map[string]map[string]RandomBattleSpecies)
+	for gen := 1; gen <= maxRandomBattleGen; gen++ {
+		for _, name := range []string{"sets.json", "data.json"} {
+			file, source, err := src.open(fmt.Sprintf("random-battles/gen%d/%s", gen, name))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			var raw map[string]rawRandomBattleSpecies
+			err = json.NewDecoder(file).Decode(&raw)
+			file.Close()
+			if err != nil {
+				return nil, fmt.Errorf("error al leer %s: %w", source, err)
+			}
+
+			species := make(map[string]RandomBattleSpecies, len(raw))
+			for id, r := range raw {
+				entry := RandomBattleSpecies{Level: r.Level, Sets: r.Sets}
+				if len(entry.Sets) == 0 && len(r.Moves) > 0 {
+					entry.Sets = []RandomBattleSet{{
+						Movepool:  r.Moves,
+						Abilities: r.Abilities,
+						Items:     r.Items,
+					}}
+				}
+				species[ToID(id)] = entry
+			}
+			formats[randomBattleFormat(gen)] = species
+			log.Printf("[Data] %d especies de random battle cargadas desde %s", len(species), source)
+			break
+		}
+	}
+	return formats, nil
+}
+
+// GetRandomBattleSets devuelve los sets posibles de una especie en un
+// formato de random battle, o false si no hay datos para ese formato.
+func (d *Dex) GetRandomBattleSets(format, species string) (RandomBattleSpecies, bool) {
+	sets, ok := d.randomSets[ToID(format)]
+	if !ok {
+		return RandomBattleSpecies{}, false
+	}
+	if entry, ok := sets[ToID(species)]; ok {
+		return entry, true
+	}
+	// Las formas cosméticas (Gastrodon-East, etc.) comparten sets con la base.
+	if base, _, found := strings.Cut(species, "-"); found {
+		entry, ok := sets[ToID(base)]
+		return entry, ok
+	}
+	return RandomBattleSpecies{}, false
+}
+
+func ToID(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,107 @@
+package data
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Los lectores toman el puntero una sola vez por consulta, así que una
+// recarga nunca les muestra una mezcla de datos viejos y nuevos.
+var current atomic.Pointer[Dex]
+
+var (
+	reloadMu sync.Mutex
+	loadDir  string
+)
+
+// Current devuelve el Dex vigente. Conviene tomarlo una vez por unidad de
+// trabajo y pasarlo hacia abajo en lugar de llamarlo en cada consulta.
+func Current() *Dex {
+	if d := current.Load(); d != nil {
+		return d
+	}
+	return &Dex{}
+}
+
+// Load carga los datos embebidos en el binario. Si dir no está vacío, cada
+// archivo presente en dir (plano o .gz) reemplaza al embebido del mismo nombre.
+func Load(dir string) (LoadReport, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	d, err := buildDex(sourceForDir(dir))
+	if err != nil {
+		return d.report, err
+	}
+	loadDir = dir
+	current.Store(d)
+	return d.report, nil
+}
+
+// Reload vuelve a leer los datos desde el mismo origen que el último Load y
+// los reemplaza de forma atómica. Si falla, se conservan los datos actuales.
+func Reload() (LoadReport, error) {
+	reloadMu.Lock()
+	dir := loadDir
+	reloadMu.Unlock()
+	return Load(dir)
+}
+
+// Watch revisa cada interval si cambió algún archivo del directorio de
+// override y recarga los datos. No hace nada si se usan solo los embebidos.
+func Watch(ctx context.Context, interval time.Duration) {
+	reloadMu.Lock()
+	dir := loadDir
+	reloadMu.Unlock()
+	if dir == "" {
+		return
+	}
+
+	last := latestModTime(dir)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			mod := latestModTime(dir)
+			if !mod.After(last) {
+				continue
+			}
+			last = mod
+			report, err := Reload()
+			if err != nil {
+				log.Printf("[Data] Error recargando datos desde %s: %v", dir, err)
+				continue
+			}
+			log.Printf("[Data] Datos recargados: %s", report)
+		}
+	}
+}
+
+func latestModTime(dir string) time.Time {
+	var latest time.Time
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	if latest.IsZero() {
+		if info, err := os.Stat(dir); err == nil {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
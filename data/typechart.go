@@ -0,0 +1,147 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Códigos de damageTaken tal como los usa Showdown en typechart.js.
+const (
+	damageNeutral = 0
+	damageWeak    = 1
+	damageResist  = 2
+	damageImmune  = 3
+)
+
+type TypeData struct {
+	DamageTaken map[string]int `json:"damageTaken"`
+}
+
+type TypeChart map[string]TypeData
+
+type EffectivenessOptions struct {
+	Gen                   int
+	MoveID                string
+	Scrappy               bool
+	RingTarget            bool
+	DefenderTerastallized bool
+}
+
+// Showdown guarda las diferencias por generación en data/mods/genN; la gen 1
+// tiene su propia tabla y las gens 2 a 5 comparten la previa a Fairy.
+var typeChartMods = map[int]string{
+	1: "gen1",
+	2: "gen5",
+	3: "gen5",
+	4: "gen5",
+	5: "gen5",
+}
+
+func loadTypeCharts(src dataSource) (map[string]TypeChart, error) {
+	charts := make(map[string]TypeChart)
+
+	chart, err := readTypeChart(src, "typechart.json")
+	if err != nil {
+		return nil, err
+	}
+	charts[""] = chart
+
+	for _, mod := range typeChartMods {
+		if _, ok := charts[mod]; ok {
+			continue
+		}
+		chart, err := readTypeChart(src, path.Join("mods", mod, "typechart.json"))
+		if err != nil {
+			return nil, err
+		}
+		charts[mod] = chart
+	}
+	return charts, nil
+}
+
+func readTypeChart(src dataSource, name string) (TypeChart, error) {
+	file, source, err := src.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var chart TypeChart
+	if err := json.NewDecoder(file).Decode(&chart); err != nil {
+		return nil, fmt.Errorf("error al leer %s: %w", source, err)
+	}
+	return chart, nil
+}
+
+func (d *Dex) typeChart(gen int) TypeChart {
+	if gen == 0 {
+		gen = d.Gen
+	}
+	if mod, ok := typeChartMods[gen]; ok {
+		if chart, ok := d.typeCharts[mod]; ok {
+			return chart
+		}
+	}
+	return d.typeCharts[""]
+}
+
+func (d *Dex) GetTypeNames() []string {
+	chart := d.typeChart(0)
+	names := make([]string, 0, len(chart))
+	for id := range chart {
+		if id == "stellar" {
+			continue
+		}
+		names = append(names, capitalizeType(id))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (d *Dex) Effectiveness(attacking string, defendingTypes []string, opts EffectivenessOptions) float64 {
+	chart := d.typeChart(opts.Gen)
+
+	if attacking == "Stellar" {
+		if opts.DefenderTerastallized {
+			return 2
+		}
+		return 1
+	}
+
+	eff := 1.0
+	for _, defending := range defendingTypes {
+		data, ok := chart[strings.ToLower(defending)]
+		if !ok {
+			continue
+		}
+		if opts.MoveID == "freezedry" && defending == "Water" {
+			eff *= 2
+			continue
+		}
+		switch data.DamageTaken[attacking] {
+		case damageWeak:
+			eff *= 2
+		case damageResist:
+			eff *= 0.5
+		case damageImmune:
+			if opts.RingTarget {
+				continue
+			}
+			if opts.Scrappy && defending == "Ghost" && (attacking == "Normal" || attacking == "Fighting") {
+				continue
+			}
+			return 0
+		}
+	}
+	return eff
+}
+
+func capitalizeType(id string) string {
+	if id == "" {
+		return id
+	}
+	return strings.ToUpper(id[:1]) + id[1:]
+}
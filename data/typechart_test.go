@@ -0,0 +1,81 @@
+package data
+
+import "testing"
+
+func testDex(t *testing.T) *Dex {
+	t.Helper()
+	d, err := NewDex(Embedded())
+	if err != nil {
+		t.Fatalf("cargando datos embebidos: %v", err)
+	}
+	return d
+}
+
+func TestEffectiveness(t *testing.T) {
+	dex := testDex(t)
+	tests := []struct {
+		name      string
+		attacking string
+		defending []string
+		opts      EffectivenessOptions
+		want      float64
+	}{
+		{"súper eficaz", "Fire", []string{"Grass"}, EffectivenessOptions{}, 2},
+		{"doble súper eficaz", "Water", []string{"Fire", "Ground"}, EffectivenessOptions{}, 4},
+		{"doble resistencia", "Fire", []string{"Water", "Dragon"}, EffectivenessOptions{}, 0.25},
+		{"se anulan", "Ice", []string{"Dragon", "Steel"}, EffectivenessOptions{}, 1},
+		{"inmune", "Electric", []string{"Water", "Ground"}, EffectivenessOptions{}, 0},
+		{"inmune aunque sea débil el otro tipo", "Ground", []string{"Fire", "Flying"}, EffectivenessOptions{}, 0},
+		{"ring target", "Ground", []string{"Fire", "Flying"}, EffectivenessOptions{RingTarget: true}, 2},
+		{"normal a fantasma", "Normal", []string{"Ghost"}, EffectivenessOptions{}, 0},
+		{"scrappy normal", "Normal", []string{"Ghost"}, EffectivenessOptions{Scrappy: true}, 1},
+		{"scrappy lucha", "Fighting", []string{"Ghost", "Normal"}, EffectivenessOptions{Scrappy: true}, 2},
+		{"scrappy no afecta otras inmunidades", "Electric", []string{"Ground"}, EffectivenessOptions{Scrappy: true}, 0},
+		{"freeze-dry a agua", "Ice", []string{"Water"}, EffectivenessOptions{MoveID: "freezedry"}, 2},
+		{"freeze-dry a agua/tierra", "Ice", []string{"Water", "Ground"}, EffectivenessOptions{MoveID: "freezedry"}, 4},
+		{"hielo normal a agua", "Ice", []string{"Water"}, EffectivenessOptions{}, 0.5},
+		{"stellar sin tera", "Stellar", []string{"Fire"}, EffectivenessOptions{}, 1},
+		{"stellar a terastalizado", "Stellar", []string{"Fire"}, EffectivenessOptions{DefenderTerastallized: true}, 2},
+		{"dragón a hada", "Dragon", []string{"Fairy"}, EffectivenessOptions{}, 0},
+		{"fantasma a acero gen 9", "Ghost", []string{"Steel"}, EffectivenessOptions{}, 1},
+		{"fantasma a acero gen 5", "Ghost", []string{"Steel"}, EffectivenessOptions{Gen: 5}, 0.5},
+		{"siniestro a acero gen 4", "Dark", []string{"Steel"}, EffectivenessOptions{Gen: 4}, 0.5},
+		{"fantasma a psíquico gen 1", "Ghost", []string{"Psychic"}, EffectivenessOptions{Gen: 1}, 0},
+		{"bicho a veneno gen 1", "Bug", []string{"Poison"}, EffectivenessOptions{Gen: 1}, 2},
+		{"hielo a fuego gen 1", "Ice", []string{"Fire"}, EffectivenessOptions{Gen: 1}, 1},
+		{"tipo desconocido", "Fire", []string{"???"}, EffectivenessOptions{}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dex.Effectiveness(tt.attacking, tt.defending, tt.opts); got != tt.want {
+				t.Errorf("Effectiveness(%s, %v) = %v, se esperaba %v", tt.attacking, tt.defending, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEffectivenessUsesDexGen(t *testing.T) {
+	dex := testDex(t).ForGen(5)
+	if got := dex.Effectiveness("Ghost", []string{"Steel"}, EffectivenessOptions{}); got != 0.5 {
+		t.Errorf("ForGen(5): fantasma a acero = %v, se esperaba 0.5", got)
+	}
+	if got := dex.Effectiveness("Ghost", []string{"Steel"}, EffectivenessOptions{Gen: 9}); got != 1 {
+		t.Errorf("Gen explícita 9 sobre un dex gen 5 = %v, se esperaba 1", got)
+	}
+}
+
+func TestGenFromFormat(t *testing.T) {
+	tests := map[string]int{
+		"gen9randombattle":       9,
+		"battle-gen4ou-123":      4,
+		"gen10ou":                10,
+		"Gen 9 OU":               0,
+		"ou":                     0,
+		"battle-gen1ubers-99999": 1,
+	}
+	for format, want := range tests {
+		if got := GenFromFormat(format); got != want {
+			t.Errorf("GenFromFormat(%q) = %d, se esperaba %d", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UsageEntry son los datos de una especie en un archivo chaos de Smogon. Los
+// mapas están indexados por ID y sus valores son conteos ponderados, no
+// porcentajes: se normalizan con RawCount.
+type UsageEntry struct {
+	RawCount  float64
+	Usage     float64
+	Abilities map[string]float64
+	Items     map[string]float64
+	Moves     map[string]float64
+	Spreads   map[string]float64
+	TeraTypes map[string]float64
+}
+
+type UsageStats struct {
+	Format  string
+	Rating  int
+	Battles int
+	Pokemon map[string]UsageEntry
+}
+
+type rawUsageStats struct {
+	Info struct {
+		Metagame string  `json:"metagame"`
+		Cutoff   float64 `json:"cutoff"`
+		Battles  int     `json:"number of battles"`
+	} `json:"info"`
+	Data map[string]struct {
+		RawCount  float64            `json:"Raw count"`
+		Usage     float64            `json:"usage"`
+		Abilities map[string]float64 `json:"Abilities"`
+		Items     map[string]float64 `json:"Items"`
+		Moves     map[string]float64 `json:"Moves"`
+		Spreads   map[string]float64 `json:"Spreads"`
+		TeraTypes map[string]float64 `json:"Tera Types"`
+	} `json:"data"`
+}
+
+const usageDir = "usage"
+
+// loadUsageStats lee los archivos chaos de Smogon ubicados en usage/, con el
+// nombre que usa smogon.com/stats: <formato>-<rating>.json.
+func loadUsageStats(src dataSource) (map[string][]*UsageStats, error) {
+	stats := make(map[string][]*UsageStats)
+	seen := make(map[string]bool)
+
+	for _, layer := range src.layers {
+		entries, err := fs.ReadDir(layer.fsys, usageDir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), ".gz")
+			if entry.IsDir() || !strings.HasSuffix(name, ".json") || seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			u, err := readUsageStats(src, path.Join(usageDir, name))
+			if err != nil {
+				return nil, err
+			}
+			stats[u.Format] = append(stats[u.Format], u)
+		}
+	}
+
+	for format := range stats {
+		sort.Slice(stats[format], func(i, j int) bool {
+			return stats[format][i].Rating < stats[format][j].Rating
+		})
+	}
+	return stats, nil
+}
+
+func readUsageStats(src dataSource, name string) (*UsageStats, error) {
+	file, source, err := src.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var raw rawUsageStats
+	if err := json.NewDecoder(file).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("error al leer %s: %w", source, err)
+	}
+
+	u := &UsageStats{
+		Format:  raw.Info.Metagame,
+		Rating:  int(raw.Info.Cutoff),
+		Battles: raw.Info.Battles,
+		Pokemon: make(map[string]UsageEntry, len(raw.Data)),
+	}
+	base := strings.TrimSuffix(path.Base(name), ".json")
+	if format, rating, ok := strings.Cut(base, "-"); ok {
+		if u.Format == "" {
+			u.Format = format
+		}
+		if r, err := strconv.Atoi(rating); err == nil && u.Rating == 0 {
+			u.Rating = r
+		}
+	}
+
+	for species, e := range raw.Data {
+		u.Pokemon[ToID(species)] = UsageEntry{
+			RawCount:  e.RawCount,
+			Usage:     e.Usage,
+			Abilities: e.Abilities,
+			Items:     e.Items,
+			Moves:     e.Moves,
+			Spreads:   e.Spreads,
+			TeraTypes: e.TeraTypes,
+		}
+	}
+	log.Printf("[Data] usage stats %s (rating %d): %d especies desde %s", u.Format, u.Rating, len(u.Pokemon), source)
+	return u, nil
+}
+
+// GetUsageEntry busca una especie en las estadísticas del formato. Con
+// rating 0 se usa el corte más alto disponible.
+func (d *Dex) GetUsageEntry(format string, rating int, species string) (UsageEntry, *UsageStats, bool) {
+	all := d.usage[ToID(format)]
+	if len(all) == 0 {
+		return UsageEntry{}, nil, false
+	}
+	stats := all[len(all)-1]
+	if rating > 0 {
+		for _, u := range all {
+			if u.Rating == rating {
+				stats = u
+			}
+		}
+	}
+	if entry, ok := stats.Pokemon[ToID(species)]; ok {
+		return entry, stats, true
+	}
+	return UsageEntry{}, stats, false
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"showdown-analizer/client"
+	"showdown-analizer/data"
+	"sync"
+	"time"
+)
+
+// Cada cuánto se consulta userdetails de un usuario seguido.
+const followInterval = 10 * time.Second
+
+// Usuarios distintos que se pueden seguir a la vez; cada uno mantiene una
+// conexión abierta con Showdown.
+const maxFollowedUsers = 20
+
+// Seguimientos abiertos a la vez desde una misma dirección.
+const maxFollowsPerClient = 3
+
+var (
+	errTooManyFollowed = errors.New("se sigue al máximo de usuarios")
+	errTooManyFollows  = errors.New("el cliente ya sigue al máximo de usuarios")
+)
+
+// IDs de sala que se aceptan de userdetails antes de mandarlos al cliente.
+var battleRoomID = regexp.MustCompile(`^battle-[a-z0-9-]+$`)
+
+// follower consulta periódicamente las salas de un usuario y avisa a los
+// que lo siguen cuando aparece una batalla nueva. Hay uno por usuario,
+// compartido entre todos los viewers.
+type follower struct {
+	user    string
+	viewers map[chan string]struct{}
+	// live son las batallas que jugaba el usuario en la última consulta.
+	live   map[string]bool
+	cancel context.CancelFunc
+}
+
+var (
+	followMu  sync.Mutex
+	followers = map[string]*follower{}
+	// clientFollows cuenta los seguimientos abiertos por dirección.
+	clientFollows = map[string]int{}
+)
+
+// subscribeFollow registra un viewer de la dirección addr para user y
+// arranca la consulta si es el primero. Por el canal llegan los IDs de sala
+// de las batallas nuevas, empezando por las que ya están en curso. Falla si
+// addr ya tiene maxFollowsPerClient seguimientos o si user es nuevo y ya se
+// siguen maxFollowedUsers.
+func subscribeFollow(user, addr string) (chan string, error) {
+	followMu.Lock()
+	defer followMu.Unlock()
+
+	if clientFollows[addr] >= maxFollowsPerClient {
+		return nil, errTooManyFollows
+	}
+	f, ok := followers[user]
+	if !ok && len(followers) >= maxFollowedUsers {
+		return nil, errTooManyFollowed
+	}
+	clientFollows[addr]++
+	ch := make(chan string, 10)
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		f = &follower{user: user, viewers: map[chan string]struct{}{}, live: map[string]bool{}, cancel: cancel}
+		followers[user] = f
+		go f.poll(ctx)
+	}
+	f.viewers[ch] = struct{}{}
+	for room := range f.live {
+		select {
+		case ch <- room:
+		default:
+		}
+	}
+	return ch, nil
+}
+
+// unsubscribeFollow da de baja el viewer de addr y frena la consulta si era
+// el último.
+func unsubscribeFollow(user, addr string, ch chan string) {
+	followMu.Lock()
+	defer followMu.Unlock()
+
+	if clientFollows[addr]--; clientFollows[addr] <= 0 {
+		delete(clientFollows, addr)
+	}
+	f, ok := followers[user]
+	if !ok {
+		return
+	}
+	delete(f.viewers, ch)
+	if len(f.viewers) == 0 {
+		f.cancel()
+		delete(followers, user)
+	}
+}
+
+func (f *follower) poll(ctx context.Context) {
+	var sc *client.ShowdownClient
+	defer func() {
+		if sc != nil {
+			sc.Conn.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	for {
+		if sc == nil {
+			var err error
+			if sc, err = client.NewShowdownClient(showdown); err != nil {
+				log.Printf("Error conectando para seguir a %s: %v", f.user, err)
+				sc = nil
+			}
+		}
+		if sc != nil {
+			details, err := sc.UserDetails(f.user)
+			if err != nil {
+				log.Printf("Error consultando userdetails de %s: %v", f.user, err)
+				sc.Conn.Close()
+				sc = nil
+			} else {
+				f.update(details.Battles())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// update reemplaza las batallas en curso y avisa de las nuevas. Las salas
+// con un ID inesperado se descartan.
+func (f *follower) update(battles []string) {
+	followMu.Lock()
+	defer followMu.Unlock()
+
+	live := make(map[string]bool, len(battles))
+	for _, room := range battles {
+		if !battleRoomID.MatchString(room) {
+			log.Printf("Sala inválida en userdetails de %s: %q", f.user, room)
+			continue
+		}
+		live[room] = true
+		if f.live[room] {
+			continue
+		}
+		log.Printf("%s empezó la batalla %s", f.user, room)
+		for ch := range f.viewers {
+			select {
+			case ch <- room:
+			default:
+				log.Printf("Viewer de %s saturado, se descarta %s", f.user, room)
+			}
+		}
+	}
+	f.live = live
+}
+
+// handleFollow es un stream SSE que avisa cada vez que user empieza una
+// batalla: manda un evento "battle" con el ID de la sala para que la UI
+// abra su análisis. Los mensajes son texto plano.
+func handleFollow(w http.ResponseWriter, r *http.Request) {
+	user := data.ToID(r.URL.Query().Get("user"))
+	if user == "" {
+		http.Error(w, "Falta el usuario a seguir", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming no soportado", http.StatusInternalServerError)
+		return
+	}
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+	ch, err := subscribeFollow(user, addr)
+	switch {
+	case errors.Is(err, errTooManyFollows):
+		http.Error(w, "Ya sigues al máximo de usuarios desde esta dirección", http.StatusTooManyRequests)
+		return
+	case err != nil:
+		http.Error(w, "Ya se sigue al máximo de usuarios, intenta más tarde", http.StatusServiceUnavailable)
+		return
+	}
+	defer unsubscribeFollow(user, addr, ch)
+
+	// Seguir a alguien dura toda su sesión de ladder: sin límite de
+	// escritura del servidor.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	log.Printf("%s sigue a %s", r.RemoteAddr, user)
+
+	fmt.Fprintf(w, "data: Siguiendo a %s. Se abre cada batalla nueva que empiece.\n\n", user)
+	flusher.Flush()
+
+	pingTicker := time.NewTicker(20 * time.Second)
+	defer pingTicker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-pingTicker.C:
+			fmt.Fprintf(w, ": ping\n\n")
+			flusher.Flush()
+		case room := <-ch:
+			fmt.Fprintf(w, "event: battle\ndata: %s\n\n", room)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package game
+
+import (
+	"maps"
+	"slices"
+)
+
+// Clone copia el estado en profundidad para poder modificarlo sin tocar el
+// original. El |request|, los snapshots y las búsquedas se comparten
+// porque nunca se modifican.
+func (s *BattleState) Clone() *BattleState {
+	c := *s
+	c.FieldEffects = maps.Clone(s.FieldEffects)
+	c.WinHistory = append([]float64(nil), s.WinHistory...)
+	c.Snapshots = slices.Clip(s.Snapshots)
+	c.Players = make(map[string]*Player, len(s.Players))
+
+	copies := map[*Pokemon]*Pokemon{}
+	for id, player := range s.Players {
+		p := *player
+		p.SideConditions = maps.Clone(player.SideConditions)
+		p.Team = make(map[string]*Pokemon, len(player.Team))
+		for name, poke := range player.Team {
+			p.Team[name] = poke.Clone()
+			copies[poke] = p.Team[name]
+		}
+		if player.Active != nil {
+			p.Active = copies[player.Active]
+		}
+		c.Players[id] = &p
+	}
+
+	c.TurnMoves = make([]MoveEvent, len(s.TurnMoves))
+	for i, event := range s.TurnMoves {
+		event.Pokemon, event.Target = copies[event.Pokemon], copies[event.Target]
+		c.TurnMoves[i] = event
+	}
+	return &c
+}
+
+func (p *Pokemon) Clone() *Pokemon {
+	c := *p
+	c.Moves = append([]Move(nil), p.Moves...)
+	c.Type = append([]string(nil), p.Type...)
+	c.BaseTypes = append([]string(nil), p.BaseTypes...)
+	c.Boosts = maps.Clone(p.Boosts)
+	c.Stats = maps.Clone(p.Stats)
+	c.StatRanges = maps.Clone(p.StatRanges)
+	if c.Boosts == nil {
+		c.Boosts = map[string]int{}
+	}
+	return &c
+}
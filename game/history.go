@@ -0,0 +1,172 @@
+package game
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Snapshot devuelve una copia del estado para guardar en el historial. No
+// arrastra los snapshots anteriores y no debe modificarse.
+func (s *BattleState) Snapshot() *BattleState {
+	c := s.Clone()
+	c.Snapshots = nil
+	return c
+}
+
+// AtTurn devuelve el estado al empezar el turno n, como se guardó al
+// recibir |turn|.
+func (s *BattleState) AtTurn(n int) (*BattleState, bool) {
+	for _, snap := range s.Snapshots {
+		if snap.Turn == n {
+			return snap, true
+		}
+	}
+	return nil, false
+}
+
+// SetSearches guarda las búsquedas del turno turn en el estado y en el
+// snapshot de ese turno. El snapshot se reemplaza por una copia porque
+// puede estar leyéndose fuera del lock.
+func (s *BattleState) SetSearches(turn int, searches map[string]SearchSummary) {
+	if s.Turn == turn {
+		s.Searches = searches
+	}
+	s.Snapshots = slices.Clone(s.Snapshots)
+	for i, snap := range s.Snapshots {
+		if snap.Turn == turn {
+			c := *snap
+			c.Searches = searches
+			s.Snapshots[i] = &c
+		}
+	}
+}
+
+// Change es una diferencia entre dos estados. Pokemon está vacío para los
+// cambios del lado o del campo y Player para los del campo.
+type Change struct {
+	Player  string `json:"player,omitempty"`
+	Pokemon string `json:"pokemon,omitempty"`
+	Field   string `json:"field"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+func (c Change) String() string {
+	who := c.Pokemon
+	if who == "" {
+		who = c.Player
+	}
+	if who != "" {
+		who += " "
+	}
+	return fmt.Sprintf("%s%s: %s → %s", who, c.Field, orDash(c.From), orDash(c.To))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Diff lista lo que cambió de from a to: campo, condiciones de cada lado,
+// activos y el estado visible de cada Pokémon.
+func Diff(from, to *BattleState) []Change {
+	var changes []Change
+	add := func(player, poke, field, a, b string) {
+		if a != b {
+			changes = append(changes, Change{Player: player, Pokemon: poke, Field: field, From: a, To: b})
+		}
+	}
+
+	add("", "", "clima", from.Weather, to.Weather)
+	add("", "", "campo", joinKeys(from.FieldEffects), joinKeys(to.FieldEffects))
+
+	for _, player := range to.OrderedPlayers() {
+		before := from.Players[player.ID]
+		if before == nil {
+			before = &Player{}
+		}
+		add(player.Name, "", "activo", activeName(before), activeName(player))
+		add(player.Name, "", "lado", sideConditions(before.SideConditions), sideConditions(player.SideConditions))
+
+		names := slices.Sorted(maps.Keys(player.Team))
+		for _, name := range names {
+			poke := player.Team[name]
+			prev := before.Team[name]
+			if prev == nil {
+				add(player.Name, name, "visto", "", "sí")
+				prev = &Pokemon{}
+			}
+			add(player.Name, name, "vida", hpString(prev), hpString(poke))
+			add(player.Name, name, "estado", prev.Status, poke.Status)
+			add(player.Name, name, "boosts", boostString(prev.Boosts), boostString(poke.Boosts))
+			add(player.Name, name, "item", prev.Item, poke.Item)
+			add(player.Name, name, "habilidad", prev.Ability, poke.Ability)
+			if poke.Terastallized != prev.Terastallized {
+				add(player.Name, name, "tera", "", poke.TeraType)
+			}
+			for _, m := range poke.Moves {
+				if !slices.ContainsFunc(prev.Moves, func(pm Move) bool { return pm.Name == m.Name }) {
+					add(player.Name, name, "movimiento", "", m.Name)
+				}
+			}
+		}
+	}
+	return changes
+}
+
+func activeName(p *Player) string {
+	if p.Active == nil {
+		return ""
+	}
+	return p.Active.Name
+}
+
+func hpString(p *Pokemon) string {
+	switch {
+	case p.Fainted:
+		return "debilitado"
+	case p.MaxHP == 0:
+		return ""
+	case p.MaxHP == 100:
+		return fmt.Sprintf("%d%%", p.HP)
+	}
+	return fmt.Sprintf("%d/%d", p.HP, p.MaxHP)
+}
+
+func boostString(boosts map[string]int) string {
+	var parts []string
+	for _, stat := range slices.Sorted(maps.Keys(boosts)) {
+		if v := boosts[stat]; v != 0 {
+			parts = append(parts, fmt.Sprintf("%+d %s", v, stat))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sideConditions(conds map[string]int) string {
+	var parts []string
+	for _, name := range slices.Sorted(maps.Keys(conds)) {
+		if n := conds[name]; n > 1 {
+			parts = append(parts, fmt.Sprintf("%s x%d", name, n))
+		} else if n == 1 {
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func joinKeys(set map[string]bool) string {
+	var keys []string
+	for k, ok := range set {
+		if ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
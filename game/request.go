@@ -0,0 +1,67 @@
+package game
+
+// Request es el JSON de |request| que Showdown envía sólo al jugador dueño
+// del lado: su equipo completo y las opciones válidas para este turno.
+type Request struct {
+	RQID        int             `json:"rqid"`
+	Wait        bool            `json:"wait"`
+	TeamPreview bool            `json:"teamPreview"`
+	ForceSwitch []bool          `json:"forceSwitch"`
+	Active      []ActiveRequest `json:"active"`
+	Side        SideRequest     `json:"side"`
+}
+
+type ActiveRequest struct {
+	Moves           []RequestMove `json:"moves"`
+	Trapped         bool          `json:"trapped"`
+	MaybeTrapped    bool          `json:"maybeTrapped"`
+	CanTerastallize string        `json:"canTerastallize"`
+}
+
+type RequestMove struct {
+	Move     string `json:"move"`
+	ID       string `json:"id"`
+	PP       int    `json:"pp"`
+	MaxPP    int    `json:"maxpp"`
+	Target   string `json:"target"`
+	Disabled bool   `json:"disabled"`
+}
+
+type SideRequest struct {
+	Name    string           `json:"name"`
+	ID      string           `json:"id"`
+	Pokemon []RequestPokemon `json:"pokemon"`
+}
+
+type RequestPokemon struct {
+	Ident         string         `json:"ident"`
+	Details       string         `json:"details"`
+	Condition     string         `json:"condition"`
+	Active        bool           `json:"active"`
+	Stats         map[string]int `json:"stats"`
+	Moves         []string       `json:"moves"`
+	BaseAbility   string         `json:"baseAbility"`
+	Ability       string         `json:"ability"`
+	Item          string         `json:"item"`
+	TeraType      string         `json:"teraType"`
+	Terastallized string         `json:"terastallized"`
+}
+
+// DisabledMove indica si el último |request| marca moveName como no
+// seleccionable para el Pokémon activo.
+func (p *Player) DisabledMove(moveName string) bool {
+	if p.Request == nil || len(p.Request.Active) == 0 {
+		return false
+	}
+	for _, m := range p.Request.Active[0].Moves {
+		if m.Move == moveName {
+			return m.Disabled
+		}
+	}
+	return false
+}
+
+// Trapped indica si el último |request| impide cambiar al Pokémon activo.
+func (p *Player) Trapped() bool {
+	return p.Request != nil && len(p.Request.Active) > 0 && p.Request.Active[0].Trapped
+}
@@ -1,35 +1,183 @@
 package game
 
+import (
+	"slices"
+	"strings"
+)
+
+type StatRange struct {
+	Min int
+	Max int
+}
+
 type Move struct {
-	Name  string
-	Type  string
-	Power int
+	Name   string
+	Type   string
+	Power  int
+	MaxPP  int
+	PPUsed int
+}
+
+// PP devuelve los PP restantes, o -1 si no se conoce el máximo.
+func (m Move) PP() int {
+	if m.MaxPP == 0 {
+		return -1
+	}
+	return max(0, m.MaxPP-m.PPUsed)
 }
 
 type Pokemon struct {
-	Name    string
-	HP      int
-	MaxHP   int
-	Fainted bool
-	Moves   []Move
-	Status  string
-	Ability string
-	Boosts  map[string]int
-	Type    []string
+	Name          string
+	Species       string
+	Level         int
+	HP            int
+	MaxHP         int
+	Fainted       bool
+	Moves         []Move
+	Status        string
+	StatusTurns   int
+	Ability       string
+	Item          string
+	TeraType      string
+	Terastallized bool
+	Boosts        map[string]int
+	// Type son los tipos actuales, el Tera si terastalizó; BaseTypes los de
+	// la especie, que conservan su STAB después de terastalizar.
+	Type      []string
+	BaseTypes []string
+
+	// Preview indica que sólo lo conocemos por team preview (|poke|) y
+	// todavía no entró al campo.
+	Preview bool
+
+	// Cotas de la Speed deducidas del orden de los turnos, sin boosts ni
+	// efectos del campo pero con el objeto: si se sospecha Choice Scarf ya
+	// incluyen su 1.5. 0 significa que todavía no hay información.
+	SpeedLower     int
+	SpeedUpper     int
+	ScarfSuspected bool
+	// SpeedBoosted marca que Protosynthesis o Quark Drive suben su Speed.
+	SpeedBoosted bool
+	// PriorityBoosted marca que Quick Claw, Quick Draw o Custap Berry se
+	// activaron para su próximo movimiento.
+	PriorityBoosted bool
+
+	// Stats exactos (atk, def, spa, spd, spe) cuando los conocemos por
+	// |request|.
+	Stats map[string]int
+
+	// Rangos de stats (hp, atk, def, spa, spd) acotados a partir del daño
+	// observado. Un stat ausente no tiene información todavía.
+	StatRanges map[string]StatRange
+}
+
+// STABTypes son los tipos con STAB de p: los de la especie y, si
+// terastalizó a algo que no sea Stellar, también el Tera.
+func (p *Pokemon) STABTypes() []string {
+	types := p.BaseTypes
+	if len(types) == 0 {
+		types = p.Type
+	}
+	if p.Terastallized && p.TeraType != "" && p.TeraType != "Stellar" && !slices.Contains(types, p.TeraType) {
+		types = append(slices.Clone(types), p.TeraType)
+	}
+	return types
+}
+
+// STAB es el multiplicador de STAB de un movimiento de tipo moveType: 1.5,
+// 2 si además es el Tera de uno de sus tipos, y Adaptability lleva 1.5 a 2
+// y 2 a 2.25. Stellar no da STAB nuevo ni se modela su 2x de un solo uso.
+func (p *Pokemon) STAB(moveType string) float64 {
+	if !slices.Contains(p.STABTypes(), moveType) {
+		return 1
+	}
+	base := p.BaseTypes
+	if len(base) == 0 {
+		base = p.Type
+	}
+	doubled := p.Terastallized && p.TeraType == moveType && slices.Contains(base, moveType)
+	adaptability := p.Ability == "Adaptability"
+	switch {
+	case doubled && adaptability:
+		return 2.25
+	case doubled, adaptability:
+		return 2
+	}
+	return 1.5
 }
 
 type Player struct {
-	ID     string
-	Name   string
-	Team   map[string]*Pokemon
-	Active *Pokemon
+	ID             string
+	Name           string
+	Team           map[string]*Pokemon
+	Active         *Pokemon
+	SideConditions map[string]int
+
+	// TeamSize es el tamaño del equipo anunciado con |teamsize|, o 0.
+	TeamSize int
+
+	// Request es el último |request| recibido; sólo existe cuando la
+	// conexión está logueada como este jugador. No se serializa: trae el
+	// equipo completo.
+	Request *Request `json:"-"`
+}
+
+type MoveEvent struct {
+	Player       string
+	Pokemon      *Pokemon
+	Move         string
+	TargetPlayer string
+	Target       *Pokemon
+	Crit         bool
+
+	// Estado del orden al momento del movimiento: el factor de Speed
+	// conocido, si había Trick Room, si algo fuera de la Speed pudo
+	// adelantarlo o atrasarlo y si pudo actuar un factor de Speed sin
+	// modelar.
+	SpeedMultiplier float64
+	TrickRoom       bool
+	OrderAltered    bool
+	SpeedUnmodeled  bool
 }
 
 type BattleState struct {
+	Format       string
+	Perspective  string
 	Players      map[string]*Player
 	Turn         int
 	Weather      string
+	WeatherTurns int
 	FieldEffects map[string]bool
+	TurnMoves    []MoveEvent
+
+	// WinHistory guarda la probabilidad de victoria de p1 al empezar cada
+	// turno, empezando por el turno 1.
+	WinHistory []float64
+
+	// Winner es el ID del jugador que ganó, cuando la batalla terminó.
+	Winner string
+
+	// Snapshots guarda una copia inmutable del estado al empezar cada
+	// turno; ver AtTurn.
+	Snapshots []*BattleState `json:"-"`
+
+	// Searches es la búsqueda de cada jugador al empezar el turno, calculada
+	// aparte porque es cara; ver SetSearches.
+	Searches map[string]SearchSummary `json:"-"`
+}
+
+// SearchSummary resume la búsqueda de un jugador en el turno Turn, con las
+// elecciones de mejor a peor.
+type SearchSummary struct {
+	Turn    int
+	Depth   int
+	Samples int
+	Choices []RankedChoice
+}
+
+type RankedChoice struct {
+	Choice string
+	Value  float64
 }
 
 func NewBattleState() *BattleState {
@@ -52,3 +200,86 @@ func (p *Player) GetOrCreatePokemon(name string) *Pokemon {
 	}
 	return poke
 }
+
+// Self devuelve el jugador desde cuya perspectiva se analiza la batalla, o
+// nil si se mira como espectador.
+func (s *BattleState) Self() *Player {
+	if s.Perspective == "" {
+		return nil
+	}
+	return s.Players[s.Perspective]
+}
+
+// Opponent devuelve el rival de Self, o nil si se mira como espectador.
+func (s *BattleState) Opponent() *Player {
+	switch s.Perspective {
+	case "p1":
+		return s.Players["p2"]
+	case "p2":
+		return s.Players["p1"]
+	}
+	return nil
+}
+
+// OrderedPlayers devuelve los jugadores en un orden estable: primero el
+// propio si hay perspectiva, y si no p1 antes que p2.
+func (s *BattleState) OrderedPlayers() []*Player {
+	ids := []string{"p1", "p2", "p3", "p4"}
+	if s.Perspective == "p2" {
+		ids = []string{"p2", "p1", "p4", "p3"}
+	}
+	players := []*Player{}
+	for _, id := range ids {
+		if p, ok := s.Players[id]; ok {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+// GetOrCreatePokemonSpecies es GetOrCreatePokemon para un Pokémon que entra
+// al campo o que describe el |request|: si todavía no hay uno con ese apodo
+// pero team preview lo anunció por especie, reutiliza esa entrada en lugar
+// de duplicarla.
+func (p *Player) GetOrCreatePokemonSpecies(name, species string) *Pokemon {
+	if poke, ok := p.Team[name]; ok {
+		poke.Preview = false
+		return poke
+	}
+	for key, poke := range p.Team {
+		if !poke.Preview || !sameSpecies(poke.Species, species) {
+			continue
+		}
+		delete(p.Team, key)
+		poke.Name = name
+		poke.Species = species
+		poke.Preview = false
+		p.Team[name] = poke
+		return poke
+	}
+	return p.GetOrCreatePokemon(name)
+}
+
+// HasSpecies indica si el equipo ya tiene un Pokémon de species, con mote o
+// sin él. species puede ser un comodín de team preview como "Urshifu-*".
+func (p *Player) HasSpecies(species string) bool {
+	for key, poke := range p.Team {
+		known := poke.Species
+		if known == "" {
+			known = key
+		}
+		if sameSpecies(species, known) || sameSpecies(known, species) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameSpecies compara especies teniendo en cuenta los comodines de team
+// preview como "Urshifu-*".
+func sameSpecies(preview, species string) bool {
+	if base, ok := strings.CutSuffix(preview, "-*"); ok {
+		return species == base || strings.HasPrefix(species, base+"-")
+	}
+	return preview == species
+}
@@ -0,0 +1,26 @@
+package game
+
+import "testing"
+
+func TestSTAB(t *testing.T) {
+	tests := []struct {
+		name     string
+		poke     Pokemon
+		moveType string
+		want     float64
+	}{
+		{"sin STAB", Pokemon{Type: []string{"Dragon", "Ground"}}, "Fire", 1},
+		{"STAB", Pokemon{Type: []string{"Dragon", "Ground"}}, "Ground", 1.5},
+		{"adaptability", Pokemon{Type: []string{"Normal"}, Ability: "Adaptability"}, "Normal", 2},
+		{"tera a otro tipo conserva el original", Pokemon{Type: []string{"Fire"}, BaseTypes: []string{"Dragon", "Ground"}, TeraType: "Fire", Terastallized: true}, "Ground", 1.5},
+		{"tera a otro tipo da STAB nuevo", Pokemon{Type: []string{"Fire"}, BaseTypes: []string{"Dragon", "Ground"}, TeraType: "Fire", Terastallized: true}, "Fire", 1.5},
+		{"tera al propio tipo", Pokemon{Type: []string{"Ground"}, BaseTypes: []string{"Dragon", "Ground"}, TeraType: "Ground", Terastallized: true}, "Ground", 2},
+		{"tera al propio tipo con adaptability", Pokemon{Type: []string{"Normal"}, BaseTypes: []string{"Normal"}, TeraType: "Normal", Terastallized: true, Ability: "Adaptability"}, "Normal", 2.25},
+		{"stellar", Pokemon{Type: []string{"Dragon", "Ground"}, BaseTypes: []string{"Dragon", "Ground"}, TeraType: "Stellar", Terastallized: true}, "Fire", 1},
+	}
+	for _, tt := range tests {
+		if got := tt.poke.STAB(tt.moveType); got != tt.want {
+			t.Errorf("%s: STAB(%s) = %v, se esperaba %v", tt.name, tt.moveType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package infer
+
+import (
+	"log"
+	"math"
+	"showdown-analizer/calc"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/predict"
+	"sort"
+	"strings"
+)
+
+// Movimientos cuyo daño no sale de atk/spa del atacante contra def/spd del
+// defensor, o que tienen potencia variable; no sirven para acotar stats.
+var unreliableMoves = map[string]bool{
+	"bodypress": true, "foulplay": true, "psyshock": true, "psystrike": true,
+	"secretsword": true, "photongeyser": true, "shellsidearm": true,
+	"lowkick": true, "grassknot": true, "heavyslam": true, "heatcrash": true,
+	"gyroball": true, "electroball": true, "eruption": true, "waterspout": true,
+	"reversal": true, "flail": true, "hex": true, "knockoff": true,
+	"seismictoss": true, "nightshade": true, "superfang": true, "ruination": true,
+	"finalgambit": true, "endeavor": true, "counter": true, "mirrorcoat": true,
+	"metalburst": true,
+}
+
+// DamageObservation es un golpe visto en el log: qué fracción de la vida
+// máxima del defensor se perdió, como intervalo por el redondeo a %.
+type DamageObservation struct {
+	Event   game.MoveEvent
+	MinFrac float64
+	MaxFrac float64
+}
+
+type interval struct {
+	lo, hi float64
+}
+
+func (a interval) overlaps(b interval) bool {
+	return a.lo <= b.hi && b.lo <= a.hi
+}
+
+// Candidates devuelve los valores posibles de stat para poke: todos los
+// repartos de EVs y naturalezas con 31 IVs, o el valor fijo de Random Battle.
+func Candidates(dex *data.Dex, format string, poke *game.Pokemon, stat string) []int {
+	if poke.Stats != nil {
+		if stat == "hp" && poke.MaxHP > 0 {
+			return []int{poke.MaxHP}
+		}
+		if v, ok := poke.Stats[stat]; ok {
+			return []int{v}
+		}
+	}
+	species := poke.Species
+	if species == "" {
+		species = poke.Name
+	}
+	base, ok := dex.GetBaseStats(species)[stat]
+	if !ok {
+		return nil
+	}
+	level := poke.Level
+	if level == 0 {
+		level = 100
+	}
+
+	value := func(ev int, nature float64) int {
+		if stat == "hp" {
+			return calc.HP(base, calc.MaxIV, ev, level)
+		}
+		return calc.Stat(base, calc.MaxIV, ev, level, nature)
+	}
+	if strings.Contains(format, "random") {
+		return []int{value(calc.RandomBattleEV, 1)}
+	}
+
+	seen := map[int]bool{}
+	var values []int
+	for ev := 0; ev <= calc.MaxEV; ev += 4 {
+		for _, nature := range []float64{0.9, 1, 1.1} {
+			v := value(ev, nature)
+			if !seen[v] {
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+	sort.Ints(values)
+
+	r, narrowed := poke.StatRanges[stat]
+	if !narrowed {
+		return values
+	}
+	var kept []int
+	for _, v := range values {
+		if v >= r.Min && v <= r.Max {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// Range es el rango actual de stat para poke, ya acotado por lo observado.
+func Range(dex *data.Dex, format string, poke *game.Pokemon, stat string) (game.StatRange, bool) {
+	values := Candidates(dex, format, poke, stat)
+	if len(values) == 0 {
+		return game.StatRange{}, false
+	}
+	return game.StatRange{Min: values[0], Max: values[len(values)-1]}, true
+}
+
+// Likely es Range angostado al valor del reparto más usado según las
+// estadísticas del formato, si ese valor sigue siendo compatible con lo
+// observado. Es lo que usan las estimaciones de daño; las observaciones
+// siguen acotando sobre el rango completo.
+func Likely(dex *data.Dex, format string, poke *game.Pokemon, stat string) (game.StatRange, bool) {
+	r, ok := Range(dex, format, poke, stat)
+	if !ok || r.Min == r.Max {
+		return r, ok
+	}
+	spread, found := predict.LikelySpread(dex, format, poke)
+	if !found {
+		return r, true
+	}
+	species := poke.Species
+	if species == "" {
+		species = poke.Name
+	}
+	level := poke.Level
+	if level == 0 {
+		level = 100
+	}
+	v := spread.Value(dex.GetBaseStats(species)[stat], level, stat)
+	if v < r.Min || v > r.Max {
+		return r, true
+	}
+	return game.StatRange{Min: v, Max: v}, true
+}
+
+// hit agrupa lo necesario para calcular un golpe con stats en rango.
+type hit struct {
+	level     int
+	power     int
+	stab      float64
+	eff       float64
+	weather   float64
+	modifier  interval
+	defMod    interval
+	burned    bool
+	attackMul float64
+	defMul    float64
+	offStat   string
+	defStat   string
+}
+
+func newHit(dex *data.Dex, state *game.BattleState, attacker, defender *game.Pokemon, moveName string) (hit, bool) {
+	move, ok := dex.GetMove(moveName)
+	if !ok || move.Power <= 0 || move.Category == "Status" || unreliableMoves[data.ToID(moveName)] {
+		return hit{}, false
+	}
+
+	h := hit{level: attacker.Level, power: move.Power, stab: 1}
+	if h.level == 0 {
+		h.level = 100
+	}
+	physical := move.Category == "Physical"
+	if physical {
+		h.offStat, h.defStat = "atk", "def"
+	} else {
+		h.offStat, h.defStat = "spa", "spd"
+	}
+
+	h.stab = attacker.STAB(move.Type)
+	h.eff = dex.Effectiveness(move.Type, defender.Type, data.EffectivenessOptions{
+		MoveID:                data.ToID(moveName),
+		Scrappy:               attacker.Ability == "Scrappy" || attacker.Ability == "Mind's Eye",
+		DefenderTerastallized: defender.Terastallized,
+	})
+	h.burned = physical && attacker.Status == "brn" && attacker.Ability != "Guts"
+	h.attackMul = calc.BoostMultiplier(attacker.Boosts[h.offStat])
+	h.defMul = calc.BoostMultiplier(defender.Boosts[h.defStat])
+
+	h.weather = calc.WeatherModifier(state.Weather, move.Type)
+	h.modifier = interval{1, 1}
+	switch data.ToID(attacker.Item) {
+	case "":
+		// Sin objeto revelado puede llevar Life Orb o un Choice.
+		h.modifier.hi = 1.5
+	case "lifeorb":
+		h.modifier = interval{1.3, 1.3}
+	case "choiceband":
+		if physical {
+			h.attackMul *= 1.5
+		}
+	case "choicespecs":
+		if !physical {
+			h.attackMul *= 1.5
+		}
+	}
+
+	h.defMod = interval{1, 1}
+	defenderItem := data.ToID(defender.Item)
+	switch {
+	case defenderItem == "eviolite":
+		h.defMod = interval{1.5, 1.5}
+	case defenderItem == "assaultvest" && !physical:
+		h.defMod = interval{1.5, 1.5}
+	case defenderItem == "" && !physical:
+		h.defMod.hi = 1.5
+	}
+
+	if side := sideOf(state, defender); side != nil && screened(side, physical) {
+		// Brick Break y compañía rompen la pantalla antes de pegar.
+		if screenBreakers[data.ToID(moveName)] || mayHave(dex, attacker, "infiltrator") {
+			return hit{}, false
+		}
+		h.modifier.lo *= 0.5
+		h.modifier.hi *= 0.5
+	}
+	if !h.applyDefensiveAbility(dex, attacker, defender, move, physical) {
+		return hit{}, false
+	}
+	return h, true
+}
+
+// Movimientos que rompen Reflect, Light Screen y Aurora Veil antes del daño.
+var screenBreakers = map[string]bool{"brickbreak": true, "psychicfangs": true, "ragingbull": true}
+
+// screened indica si side tiene una pantalla que reduce el daño de la
+// categoría del golpe.
+func screened(side *game.Player, physical bool) bool {
+	if side.SideConditions["Aurora Veil"] > 0 {
+		return true
+	}
+	if physical {
+		return side.SideConditions["Reflect"] > 0
+	}
+	return side.SideConditions["Light Screen"] > 0
+}
+
+// sideOf devuelve el jugador al que pertenece poke.
+func sideOf(state *game.BattleState, poke *game.Pokemon) *game.Player {
+	for _, player := range state.Players {
+		for _, p := range player.Team {
+			if p == poke {
+				return player
+			}
+		}
+		if player.Active == poke {
+			return player
+		}
+	}
+	return nil
+}
+
+// possibleAbilities devuelve la habilidad revelada de poke o, si no hay,
+// todas las de su especie.
+func possibleAbilities(dex *data.Dex, poke *game.Pokemon) []string {
+	if poke.Ability != "" {
+		return []string{poke.Ability}
+	}
+	species := poke.Species
+	if species == "" {
+		species = poke.Name
+	}
+	return dex.GetPokemonAbilities(species)
+}
+
+// mayHave indica si poke tiene o puede tener la habilidad id.
+func mayHave(dex *data.Dex, poke *game.Pokemon, id string) bool {
+	for _, a := range possibleAbilities(dex, poke) {
+		if data.ToID(a) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// abilityMod son los factores de una habilidad defensiva sobre el ataque,
+// la defensa y el daño final. unknown marca efectos que dependen de algo
+// que no se sigue, como la vida justo antes del golpe para Multiscale.
+type abilityMod struct {
+	attack, defense, damage float64
+	unknown                 bool
+}
+
+// defensiveAbility devuelve el efecto de la habilidad id del defensor sobre
+// el golpe, y false si no lo cambia.
+func defensiveAbility(id string, defender *game.Pokemon, move data.MoveData, physical bool, eff float64) (abilityMod, bool) {
+	m := abilityMod{attack: 1, defense: 1, damage: 1}
+	switch {
+	case id == "multiscale" || id == "shadowshield":
+		m.unknown = true
+	case id == "furcoat" && physical:
+		m.defense = 2
+	case id == "marvelscale" && physical && defender.Status != "":
+		m.defense = 1.5
+	case id == "icescales" && !physical:
+		m.damage = 0.5
+	case id == "fluffy" && (move.Contact || move.Type == "Fire"):
+		if move.Contact {
+			m.damage *= 0.5
+		}
+		if move.Type == "Fire" {
+			m.damage *= 2
+		}
+	case (id == "filter" || id == "solidrock" || id == "prismarmor") && eff > 1:
+		m.damage = 0.75
+	case id == "thickfat" && (move.Type == "Fire" || move.Type == "Ice"):
+		m.attack = 0.5
+	case (id == "waterbubble" || id == "heatproof") && move.Type == "Fire":
+		m.attack = 0.5
+	case id == "purifyingsalt" && move.Type == "Ghost":
+		m.attack = 0.5
+	case id == "punkrock" && move.Sound:
+		m.damage = 0.5
+	case id == "dryskin" && move.Type == "Fire":
+		m.damage = 1.25
+	default:
+		return m, false
+	}
+	return m, true
+}
+
+// applyDefensiveAbility aplica la habilidad del defensor al golpe. Devuelve
+// false si el golpe no sirve para acotar: la habilidad no se reveló y
+// alguna posible lo cambiaría, o su efecto no se puede calcular.
+func (h *hit) applyDefensiveAbility(dex *data.Dex, attacker, defender *game.Pokemon, move data.MoveData, physical bool) bool {
+	if defender.Ability == "" {
+		for _, a := range possibleAbilities(dex, defender) {
+			if _, affects := defensiveAbility(data.ToID(a), defender, move, physical, h.eff); affects {
+				return false
+			}
+		}
+		return true
+	}
+	m, affects := defensiveAbility(data.ToID(defender.Ability), defender, move, physical, h.eff)
+	if !affects {
+		return true
+	}
+	// Mold Breaker y similares ignoran la habilidad del defensor.
+	for _, id := range []string{"moldbreaker", "teravolt", "turboblaze"} {
+		if mayHave(dex, attacker, id) {
+			return false
+		}
+	}
+	if m.unknown {
+		return false
+	}
+	h.attackMul *= m.attack
+	h.defMul *= m.defense
+	h.modifier.lo *= m.damage
+	h.modifier.hi *= m.damage
+	return true
+}
+
+// frac calcula el intervalo de fracción de vida que quita el golpe con el
+// ataque en atk, la defensa en def y la vida máxima en hp.
+func (h hit) frac(atk, def, hp game.StatRange) interval {
+	lowIn := calc.DamageInput{
+		Level: h.level, Power: h.power,
+		Attack:  int(float64(atk.Min) * h.attackMul),
+		Defense: int(float64(def.Max) * h.defMul * h.defMod.hi),
+		STAB:    h.stab, Effectiveness: h.eff, Weather: h.weather,
+		Modifier: h.modifier.lo, Burned: h.burned,
+	}
+	highIn := lowIn
+	highIn.Attack = int(float64(atk.Max) * h.attackMul)
+	highIn.Defense = int(float64(def.Min) * h.defMul * h.defMod.lo)
+	highIn.Modifier = h.modifier.hi
+
+	lo, _ := calc.Damage(lowIn)
+	_, hi := calc.Damage(highIn)
+	if hp.Max <= 0 || hp.Min <= 0 {
+		return interval{0, math.Inf(1)}
+	}
+	return interval{float64(lo) / float64(hp.Max), float64(hi) / float64(hp.Min)}
+}
+
+// ObserveDamage acota el stat ofensivo del atacante y la vida y defensa del
+// defensor con un golpe observado. Si ningún valor es compatible (por algo
+// que el modelo no contempla) no se toca nada.
+func ObserveDamage(dex *data.Dex, state *game.BattleState, obs DamageObservation) {
+	attacker, defender := obs.Event.Pokemon, obs.Event.Target
+	if attacker == nil || defender == nil || obs.Event.Crit || attacker == defender {
+		return
+	}
+	h, ok := newHit(dex, state, attacker, defender, obs.Event.Move)
+	if !ok || h.eff == 0 {
+		return
+	}
+
+	atk, okA := Range(dex, state.Format, attacker, h.offStat)
+	def, okD := Range(dex, state.Format, defender, h.defStat)
+	hp, okH := Range(dex, state.Format, defender, "hp")
+	if !okA || !okD || !okH {
+		return
+	}
+	observed := interval{obs.MinFrac, obs.MaxFrac}
+
+	narrow(dex, state.Format, attacker, h.offStat, func(v int) bool {
+		return h.frac(game.StatRange{Min: v, Max: v}, def, hp).overlaps(observed)
+	})
+	narrow(dex, state.Format, defender, h.defStat, func(v int) bool {
+		return h.frac(atk, game.StatRange{Min: v, Max: v}, hp).overlaps(observed)
+	})
+	narrow(dex, state.Format, defender, "hp", func(v int) bool {
+		return h.frac(atk, def, game.StatRange{Min: v, Max: v}).overlaps(observed)
+	})
+}
+
+func narrow(dex *data.Dex, format string, poke *game.Pokemon, stat string, consistent func(int) bool) {
+	var kept []int
+	for _, v := range Candidates(dex, format, poke, stat) {
+		if consistent(v) {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == 0 {
+		log.Printf("[Infer] %s: ningún valor de %s explica el daño observado, se ignora", poke.Name, stat)
+		return
+	}
+	if poke.StatRanges == nil {
+		poke.StatRanges = make(map[string]game.StatRange)
+	}
+	poke.StatRanges[stat] = game.StatRange{Min: kept[0], Max: kept[len(kept)-1]}
+}
+
+// EstimateDamage calcula el daño de move como fracción de la vida máxima del
+// defensor usando los rangos de stats acotados hasta ahora, o el reparto más
+// probable cuando hay estadísticas de uso.
+func EstimateDamage(dex *data.Dex, state *game.BattleState, attacker, defender *game.Pokemon, move string) (float64, float64, bool) {
+	h, ok := newHit(dex, state, attacker, defender, move)
+	if !ok {
+		return 0, 0, false
+	}
+	atk, okA := Likely(dex, state.Format, attacker, h.offStat)
+	def, okD := Likely(dex, state.Format, defender, h.defStat)
+	hp, okH := Likely(dex, state.Format, defender, "hp")
+	if !okA || !okD || !okH {
+		return 0, 0, false
+	}
+	f := h.frac(atk, def, hp)
+	return f.lo, f.hi, true
+}
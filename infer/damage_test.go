@@ -0,0 +1,220 @@
+package infer
+
+import (
+	"showdown-analizer/calc"
+	"showdown-analizer/data"
+	"showdown-analizer/data/datatest"
+	"showdown-analizer/game"
+	"testing"
+)
+
+func TestCandidates(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	tests := []struct {
+		name     string
+		format   string
+		poke     game.Pokemon
+		stat     string
+		min, max int
+		count    int
+	}{
+		{"sin información", "gen9ou", game.Pokemon{Species: "Garchomp"}, "spe", 216, 333, 0},
+		{"vida sin información", "gen9ou", game.Pokemon{Species: "Garchomp"}, "hp", 357, 420, 0},
+		{"random battle", "gen9randombattle", game.Pokemon{Species: "Garchomp", Level: 84}, "spe", 220, 220, 1},
+		{"stat conocido", "gen9ou", game.Pokemon{Species: "Garchomp", Stats: map[string]int{"spe": 333}}, "spe", 333, 333, 1},
+		{"vida conocida", "gen9ou", game.Pokemon{Species: "Garchomp", MaxHP: 357, Stats: map[string]int{}}, "hp", 357, 357, 1},
+		{"acotado", "gen9ou", game.Pokemon{Species: "Garchomp", StatRanges: map[string]game.StatRange{"spe": {Min: 300, Max: 320}}}, "spe", 300, 320, 0},
+		{"especie desconocida", "gen9ou", game.Pokemon{Species: "Missingno"}, "spe", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := Candidates(dex, tt.format, &tt.poke, tt.stat)
+			if tt.max == 0 {
+				if len(values) != 0 {
+					t.Fatalf("se esperaban 0 candidatos, hay %v", values)
+				}
+				return
+			}
+			if len(values) == 0 {
+				t.Fatal("no hay candidatos")
+			}
+			if values[0] != tt.min || values[len(values)-1] != tt.max {
+				t.Errorf("candidatos %d-%d, se esperaba %d-%d", values[0], values[len(values)-1], tt.min, tt.max)
+			}
+			if tt.count > 0 && len(values) != tt.count {
+				t.Errorf("%d candidatos, se esperaban %d", len(values), tt.count)
+			}
+		})
+	}
+}
+
+// earthquake arma un Earthquake de Garchomp a un Tyranitar 0 HP / 0 Def
+// (341 HP, 256 Def) cuyos stats conocemos, con ambos objetos revelados.
+func earthquake(dex *data.Dex) (*game.BattleState, *game.Pokemon, *game.Pokemon) {
+	state := game.NewBattleState()
+	state.Format = "gen9ou"
+	attacker := &game.Pokemon{Name: "Garchomp", Species: "Garchomp", Type: dex.GetPokemonTypes("Garchomp"), Item: "Leftovers"}
+	defender := &game.Pokemon{
+		Name: "Tyranitar", Species: "Tyranitar", Type: dex.GetPokemonTypes("Tyranitar"), Item: "Leftovers",
+		MaxHP: 341, Stats: map[string]int{"def": 256},
+	}
+	return state, attacker, defender
+}
+
+func TestObserveDamage(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	state, attacker, defender := earthquake(dex)
+
+	// La tirada mínima de un Garchomp Jolly 252 Atk (359): descarta tanto
+	// los ataques que no llegan como los que no pueden tirar tan bajo.
+	lo, _ := calc.Damage(calc.DamageInput{Level: 100, Power: 100, Attack: 359, Defense: 256, STAB: 1.5, Effectiveness: 2})
+	ObserveDamage(dex, state, DamageObservation{
+		Event:   game.MoveEvent{Pokemon: attacker, Target: defender, Move: "Earthquake"},
+		MinFrac: float64(lo) / 341,
+		MaxFrac: float64(lo) / 341,
+	})
+
+	r, ok := attacker.StatRanges["atk"]
+	if !ok {
+		t.Fatal("no se acotó el ataque")
+	}
+	if r != (game.StatRange{Min: 302, Max: 359}) {
+		t.Errorf("el ataque quedó en %d-%d, se esperaba 302-359", r.Min, r.Max)
+	}
+	if got := defender.StatRanges["def"]; got != (game.StatRange{Min: 256, Max: 256}) {
+		t.Errorf("la defensa conocida quedó en %v", got)
+	}
+}
+
+func TestObserveDamageIgnoresInconsistent(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	state, attacker, defender := earthquake(dex)
+
+	// Ningún reparto llega a quitar sólo un 1%.
+	ObserveDamage(dex, state, DamageObservation{
+		Event:   game.MoveEvent{Pokemon: attacker, Target: defender, Move: "Earthquake"},
+		MinFrac: 0.005,
+		MaxFrac: 0.015,
+	})
+	if _, ok := attacker.StatRanges["atk"]; ok {
+		t.Errorf("se acotó el ataque con un daño imposible: %v", attacker.StatRanges["atk"])
+	}
+}
+
+func TestObserveDamageSkips(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	tests := []struct {
+		name  string
+		event func(a, d *game.Pokemon) game.MoveEvent
+	}{
+		{"crítico", func(a, d *game.Pokemon) game.MoveEvent {
+			return game.MoveEvent{Pokemon: a, Target: d, Move: "Earthquake", Crit: true}
+		}},
+		{"daño fijo", func(a, d *game.Pokemon) game.MoveEvent {
+			return game.MoveEvent{Pokemon: a, Target: d, Move: "Seismic Toss"}
+		}},
+		{"de estado", func(a, d *game.Pokemon) game.MoveEvent {
+			return game.MoveEvent{Pokemon: a, Target: d, Move: "Swords Dance"}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, attacker, defender := earthquake(dex)
+			ObserveDamage(dex, state, DamageObservation{Event: tt.event(attacker, defender), MinFrac: 0.4, MaxFrac: 0.5})
+			if len(attacker.StatRanges) != 0 || len(defender.StatRanges) != 0 {
+				t.Errorf("se acotaron stats: %v %v", attacker.StatRanges, defender.StatRanges)
+			}
+		})
+	}
+}
+
+func TestEstimateDamage(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	state, attacker, defender := earthquake(dex)
+	attacker.Stats = map[string]int{"atk": 359}
+
+	lo, hi := calc.Damage(calc.DamageInput{Level: 100, Power: 100, Attack: 359, Defense: 256, STAB: 1.5, Effectiveness: 2})
+	minFrac, maxFrac, ok := EstimateDamage(dex, state, attacker, defender, "Earthquake")
+	if !ok {
+		t.Fatal("no se pudo estimar el daño")
+	}
+	if minFrac != float64(lo)/341 || maxFrac != float64(hi)/341 {
+		t.Errorf("EstimateDamage = %.3f-%.3f, se esperaba %.3f-%.3f", minFrac, maxFrac, float64(lo)/341, float64(hi)/341)
+	}
+
+	if _, _, ok := EstimateDamage(dex, state, attacker, defender, "Swords Dance"); ok {
+		t.Error("un movimiento de estado no debería tener daño")
+	}
+}
+
+func TestObserveDamageBehindReflect(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	state, attacker, defender := earthquake(dex)
+	state.Players["p2"] = &game.Player{
+		ID: "p2", Active: defender, Team: map[string]*game.Pokemon{"Tyranitar": defender},
+		SideConditions: map[string]int{"Reflect": 1},
+	}
+
+	// La misma tirada mínima que sin pantalla, reducida a la mitad.
+	lo, _ := calc.Damage(calc.DamageInput{Level: 100, Power: 100, Attack: 359, Defense: 256, STAB: 1.5, Effectiveness: 2, Modifier: 0.5})
+	ObserveDamage(dex, state, DamageObservation{
+		Event:   game.MoveEvent{Pokemon: attacker, Target: defender, Move: "Earthquake"},
+		MinFrac: float64(lo) / 341,
+		MaxFrac: float64(lo) / 341,
+	})
+	r, ok := attacker.StatRanges["atk"]
+	if !ok {
+		t.Fatal("no se acotó el ataque")
+	}
+	if r.Max < 359 || r.Min > 359 {
+		t.Errorf("el ataque quedó en %d-%d y no incluye 359: no se aplicó Reflect", r.Min, r.Max)
+	}
+}
+
+func TestObserveDamageSkipsUnknownModifiers(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	tests := []struct {
+		name    string
+		move    string
+		prepare func(state *game.BattleState, defender *game.Pokemon)
+	}{
+		{"multiscale posible", "Earthquake", func(_ *game.BattleState, d *game.Pokemon) {
+			d.Name, d.Species = "Dragonite", "Dragonite"
+		}},
+		{"multiscale revelada", "Earthquake", func(_ *game.BattleState, d *game.Pokemon) {
+			d.Name, d.Species, d.Ability = "Dragonite", "Dragonite", "Multiscale"
+		}},
+		{"brick break contra reflect", "Brick Break", func(s *game.BattleState, d *game.Pokemon) {
+			s.Players["p2"] = &game.Player{ID: "p2", Active: d, SideConditions: map[string]int{"Reflect": 1}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, attacker, defender := earthquake(dex)
+			tt.prepare(state, defender)
+			ObserveDamage(dex, state, DamageObservation{
+				Event:   game.MoveEvent{Pokemon: attacker, Target: defender, Move: tt.move},
+				MinFrac: 0.4, MaxFrac: 0.5,
+			})
+			if len(attacker.StatRanges) != 0 {
+				t.Errorf("se acotó el ataque: %v", attacker.StatRanges)
+			}
+		})
+	}
+}
+
+func TestEstimateDamageFurCoat(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	state, attacker, defender := earthquake(dex)
+	attacker.Stats = map[string]int{"atk": 359}
+
+	_, plain, _ := EstimateDamage(dex, state, attacker, defender, "Earthquake")
+	defender.Ability = "Fur Coat"
+	_, coat, ok := EstimateDamage(dex, state, attacker, defender, "Earthquake")
+	if !ok {
+		t.Fatal("no se pudo estimar el daño con Fur Coat")
+	}
+	if coat >= plain*0.6 {
+		t.Errorf("con Fur Coat %.3f, sin %.3f: no se duplicó la defensa", coat, plain)
+	}
+}
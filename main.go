@@ -2,15 +2,22 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"showdown-analizer/analysis"
 	"showdown-analizer/client"
 	"showdown-analizer/data"
 	"showdown-analizer/game"
 	"showdown-analizer/parser"
+	"showdown-analizer/replay"
+	"showdown-analizer/winprob"
 	"strings"
 	"time"
 )
@@ -21,6 +28,10 @@ func parseTemplates() *template.Template {
 
 var templates = parseTemplates()
 
+// showdown es el servidor al que se conectan los handlers; main lo fija
+// con los flags antes de servir.
+var showdown = client.DefaultConfig()
+
 func handleIndex(w http.ResponseWriter, r *http.Request) {
 	err := templates.ExecuteTemplate(w, "index.html", nil)
 	if err != nil {
@@ -74,7 +85,31 @@ func handleConnect(w http.ResponseWriter, r *http.Request) {
 		close(messages)
 	}()
 
-	battleState := game.NewBattleState()
+	perspective := r.URL.Query().Get("perspective")
+	switch perspective {
+	case "p1", "p2":
+	case "", "spectator":
+		perspective = ""
+	default:
+		http.Error(w, "Perspectiva inválida: usa p1, p2 o spectator", http.StatusBadRequest)
+		return
+	}
+
+	battleState := newBattleState(roomID, perspective)
+	gen := data.GenFromFormat(battleState.Format)
+	// La cuenta de SHOWDOWN_USERNAME ve el |request| de sus batallas: sólo
+	// se usa con el token y para el lado que de verdad juega.
+	username := os.Getenv("SHOWDOWN_USERNAME")
+	useLogin := perspective != "" && username != "" && loginAuthorized(r)
+	live := registerBattle(roomID, perspective, battleState, useLogin)
+	defer unregisterBattle(roomID, perspective, live)
+	// battleLines guarda el log completo para la revisión post-partida.
+	var battleLines []string
+	loggedIn := false
+	if perspective != "" && username != "" && !useLogin {
+		fmt.Fprintf(w, "data: <p class='warning'>Sin token de la cuenta se analiza como %s pero sin |request|.</p>\n\n", perspective)
+		flusher.Flush()
+	}
 
 	reconnectAttempts := 0
 	const maxReconnects = 3
@@ -83,7 +118,7 @@ func handleConnect(w http.ResponseWriter, r *http.Request) {
 
 reconnect:
 	log.Printf("Creating Showdown client (attempt %d)...", reconnectAttempts+1)
-	sdClient, err = client.NewShowdownClient()
+	sdClient, err = client.NewShowdownClient(showdown)
 	if err != nil {
 		log.Printf("Error al conectar con Showdown: %v", err)
 		fmt.Fprintf(w, "data: <p>Error al conectar con Showdown: %v</p>\n\n", err)
@@ -103,6 +138,16 @@ reconnect:
 
 	log.Printf("Showdown client created successfully")
 
+	if useLogin {
+		loggedIn = true
+		if err := sdClient.Login(username, os.Getenv("SHOWDOWN_PASSWORD")); err != nil {
+			loggedIn = false
+			log.Printf("Error al loguearse como %s: %v", username, err)
+			fmt.Fprintf(w, "data: <p class='warning'>No se pudo iniciar sesión en Showdown; se analiza sin |request|.</p>\n\n")
+			flusher.Flush()
+		}
+	}
+
 	log.Printf("Attempting to join room: %s", roomID)
 	if err := sdClient.JoinRoom(roomID); err != nil {
 		log.Printf("Error al unirse a la sala: %v", err)
@@ -112,7 +157,7 @@ reconnect:
 	}
 
 	log.Printf("Successfully joined room: %s", roomID)
-	fmt.Fprintf(w, "data: <p>Conectado a la sala <strong>%s</strong>. Esperando eventos...</p>\n\n", roomID)
+	fmt.Fprintf(w, "data: <p>Conectado a la sala <strong>%s</strong>. Esperando eventos...</p>\n\n", template.HTMLEscapeString(roomID))
 	flusher.Flush()
 
 	go func() {
@@ -201,10 +246,29 @@ reconnect:
 					return
 				}
 			}
+			dex := data.Current().ForGen(gen)
 			lines := strings.Split(msg, "\n")
 			var anyLogSent bool
 			var battleEnded bool
+			var sideMismatch bool
+			live.mu.Lock()
+			turn := battleState.Turn
 			for _, line := range lines {
+				if loggedIn && wrongSide(line, perspective, username) {
+					sideMismatch = true
+					break
+				}
+				if strings.HasPrefix(line, "|init|") {
+					// Al reconectar Showdown vuelve a mandar el log entero:
+					// se empieza de cero para no duplicar historial,
+					// snapshots ni PP.
+					battleLines = nil
+					battleState = newBattleState(roomID, perspective)
+					live.state = battleState
+					turn = -1
+				}
+				parser.ProcessLine(dex, battleState, line)
+				battleLines = append(battleLines, line)
 				if strings.HasPrefix(line, "|turn|") ||
 					strings.HasPrefix(line, "|move|") ||
 					strings.HasPrefix(line, "|switch|") ||
@@ -215,7 +279,6 @@ reconnect:
 					strings.HasPrefix(line, "|win|") ||
 					strings.HasPrefix(line, "|lose|") ||
 					strings.HasPrefix(line, "|player|") {
-					parser.ProcessLine(battleState, line)
 					log.Printf("Enviando al frontend: %s", line)
 					fmt.Fprintf(w, "data: <p class='logline'>%s</p>\n\n", template.HTMLEscapeString(line))
 					flusher.Flush()
@@ -225,12 +288,37 @@ reconnect:
 					}
 				}
 			}
+			if sideMismatch {
+				live.mu.Unlock()
+				log.Printf("%s pidió %s como %s, pero %s no juega de ese lado", r.RemoteAddr, roomID, perspective, username)
+				fmt.Fprintf(w, "data: <p class='error'>La cuenta logueada no juega como %s en esta sala.</p>\n\n", perspective)
+				flusher.Flush()
+				return
+			}
+			if battleState.Turn != turn {
+				// La búsqueda es cara: corre una vez por turno sobre una
+				// copia, sin frenar a /state.
+				snap := battleState.Snapshot()
+				live.mu.Unlock()
+				searches := parser.SearchTurn(dex, snap)
+				live.mu.Lock()
+				battleState.SetSearches(snap.Turn, searches)
+			}
+			var summary string
+			if anyLogSent {
+				summary = parser.RenderBattleState(dex, battleState)
+			}
+			live.mu.Unlock()
 			if anyLogSent {
-				summary := parser.RenderBattleState(battleState)
 				fmt.Fprintf(w, "data: %s\n\n", summary)
 				flusher.Flush()
 			}
 			if battleEnded {
+				fmt.Fprintf(w, "data: <p>Analizando la partida...</p>\n\n")
+				flusher.Flush()
+				review := replay.ReviewLog(ctx, dex, battleState, battleLines, replay.AnalyzeOptions)
+				fmt.Fprintf(w, "data: %s\n\n", replay.RenderReview(review))
+				flusher.Flush()
 				log.Printf("Batalla terminada en room %s, cerrando SOLO esta conexión SSE.", roomID)
 				fmt.Fprintf(w, "data: <p class='success'>¡Batalla terminada! El servidor sigue funcionando para nuevas conexiones.</p>\n\n")
 				flusher.Flush()
@@ -243,6 +331,125 @@ reconnect:
 	}
 }
 
+func handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	if !bearerAuthorized(r, token) {
+		http.Error(w, "No autorizado", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := data.Reload()
+	if err != nil {
+		log.Printf("Error recargando datos: %v", err)
+		http.Error(w, fmt.Sprintf("Error recargando datos: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Datos recargados desde /admin/reload: %s", report)
+	fmt.Fprintf(w, "Datos recargados: %s\n", report)
+}
+
+// bearerAuthorized compara el header Authorization con token en tiempo
+// constante.
+func bearerAuthorized(r *http.Request, token string) bool {
+	got := []byte(r.Header.Get("Authorization"))
+	return subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) == 1
+}
+
+// loginAuthorized indica si la petición puede usar la cuenta de
+// SHOWDOWN_USERNAME. Hace falta LOGIN_TOKEN, por header o por ?token=
+// porque EventSource no manda headers; sin LOGIN_TOKEN no puede nadie.
+func loginAuthorized(r *http.Request) bool {
+	token := os.Getenv("LOGIN_TOKEN")
+	if token == "" {
+		return false
+	}
+	if bearerAuthorized(r, token) {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) == 1
+}
+
+// wrongSide indica si line muestra que username no juega como perspective:
+// un |player| de ese lado con otro nombre o un |request| del otro lado.
+func wrongSide(line, perspective, username string) bool {
+	switch {
+	case strings.HasPrefix(line, "|player|"):
+		parts := strings.Split(line, "|")
+		return len(parts) >= 4 && parts[2] == perspective && parts[3] != "" && data.ToID(parts[3]) != data.ToID(username)
+	case strings.HasPrefix(line, "|request|"):
+		var req struct {
+			Side struct {
+				ID string `json:"id"`
+			} `json:"side"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "|request|")), &req); err != nil {
+			return false
+		}
+		return req.Side.ID != "" && req.Side.ID != perspective
+	}
+	return false
+}
+
+// handleTeamPreview recibe el log de protocolo hasta el team preview y
+// devuelve el análisis de ambos equipos en JSON.
+func handleTeamPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "No se pudo leer el log", http.StatusBadRequest)
+		return
+	}
+	perspective := r.URL.Query().Get("perspective")
+	if perspective == "spectator" {
+		perspective = ""
+	}
+	if perspective != "" && perspective != "p1" && perspective != "p2" {
+		http.Error(w, "Perspectiva inválida", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	dex := data.Current().ForGen(data.GenFromFormat(format))
+	state := game.NewBattleState()
+	state.Format = format
+	state.Perspective = perspective
+	for _, line := range strings.Split(string(body), "\n") {
+		parser.ProcessLine(dex, state, line)
+	}
+	if state.Format != format {
+		// La línea |tier| pudo fijar el formato; el dex tiene que coincidir.
+		dex = data.Current().ForGen(data.GenFromFormat(state.Format))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(analysis.BuildTeamPreview(dex, state)); err != nil {
+		log.Printf("Error escribiendo team preview: %v", err)
+	}
+}
+
+func formatFromRoomID(roomID string) string {
+	format, _, _ := strings.Cut(strings.TrimPrefix(roomID, "battle-"), "-")
+	return format
+}
+
+func envOr(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
+
 func getPort() string {
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -254,11 +461,27 @@ func getPort() string {
 func main() {
 	log.Printf("Iniciando servidor Showdown Analyzer...")
 
-	if err := data.LoadPokemonData("data/pokedex.json"); err != nil {
-		log.Fatalf("Error cargando datos de Pokémon: %v", err)
+	dataDir := flag.String("data-dir", os.Getenv("SHOWDOWN_DATA_DIR"), "directorio con archivos de datos que reemplazan a los embebidos")
+	dataWatch := flag.Duration("data-watch", 30*time.Second, "intervalo para detectar cambios en -data-dir (0 desactiva)")
+	serverURL := flag.String("server", envOr("SHOWDOWN_SERVER_URL", client.DefaultServerURL), "websocket del servidor de Showdown")
+	loginURL := flag.String("login-server", envOr("SHOWDOWN_LOGIN_URL", client.DefaultLoginServerURL), "action.php para firmar el login (vacío para servidores locales)")
+	winprobWeights := flag.String("winprob-weights", os.Getenv("SHOWDOWN_WINPROB_WEIGHTS"), "pesos de probabilidad de victoria generados con cmd/calibrate")
+	flag.Parse()
+	showdown = client.Config{ServerURL: *serverURL, LoginServerURL: *loginURL}
+
+	report, err := data.Load(*dataDir)
+	if err != nil {
+		log.Fatalf("Error cargando datos del juego: %v", err)
+	}
+	log.Printf("Datos cargados: %s", report)
+	if *winprobWeights != "" {
+		if err := winprob.LoadWeights(*winprobWeights); err != nil {
+			log.Fatalf("Error cargando pesos de probabilidad de victoria: %v", err)
+		}
+		log.Printf("Pesos de probabilidad de victoria cargados de %s", *winprobWeights)
 	}
-	if err := data.LoadMoveData("data/moves.json"); err != nil {
-		log.Fatalf("Error cargando datos de movimientos: %v", err)
+	if *dataWatch > 0 {
+		go data.Watch(context.Background(), *dataWatch)
 	}
 
 	mux := http.NewServeMux()
@@ -268,6 +491,12 @@ func main() {
 
 	mux.HandleFunc("/", handleIndex)
 	mux.HandleFunc("/connect", handleConnect)
+	mux.HandleFunc("/admin/reload", handleAdminReload)
+	mux.HandleFunc("/api/teampreview", handleTeamPreview)
+	mux.HandleFunc("/battles", handleBattles)
+	mux.HandleFunc("/follow", handleFollow)
+	mux.HandleFunc("/replay", handleReplay)
+	mux.HandleFunc("/state", handleState)
 
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
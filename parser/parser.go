@@ -2,24 +2,28 @@ package parser
 
 import (
 	"log"
+	"math"
 	"showdown-analizer/data"
 	"showdown-analizer/game"
+	"showdown-analizer/infer"
+	"showdown-analizer/speed"
+	"showdown-analizer/winprob"
 	"strconv"
 	"strings"
 )
 
-func ParseLog(logText string) (*game.BattleState, error) {
+func ParseLog(dex *data.Dex, logText string) (*game.BattleState, error) {
 	state := game.NewBattleState()
 	lines := strings.Split(logText, "\n")
 
 	for _, line := range lines {
-		ProcessLine(state, line)
+		ProcessLine(dex, state, line)
 	}
 
 	return state, nil
 }
 
-func ProcessLine(state *game.BattleState, line string) {
+func ProcessLine(dex *data.Dex, state *game.BattleState, line string) {
 	parts := strings.Split(strings.TrimSpace(line), "|")
 	if len(parts) < 2 {
 		return
@@ -41,11 +45,14 @@ func ProcessLine(state *game.BattleState, line string) {
 	case "poke":
 		if len(parts) >= 4 {
 			id := parts[2]
-			pokeInfo := strings.Split(parts[3], ",")
-			name := strings.TrimSpace(pokeInfo[0])
-			types := data.GetPokemonTypes(name)
-			if player, ok := state.Players[id]; ok {
-				player.Team[name] = &game.Pokemon{Name: name, Type: types}
+			name, level, _ := parseDetails(parts[3])
+			types := dex.GetPokemonTypes(name)
+			// Al reconectar se repite el preview, y el |request| pudo cargarlo
+			// antes con su mote: nunca se pisa lo que ya está.
+			if player, ok := state.Players[id]; ok && !player.HasSpecies(name) {
+				if _, taken := player.Team[name]; !taken {
+					player.Team[name] = &game.Pokemon{Name: name, Species: name, Level: level, Type: types, Boosts: map[string]int{}, Preview: true}
+				}
 			}
 		}
 	case "team":
@@ -55,8 +62,7 @@ func ProcessLine(state *game.BattleState, line string) {
 			moveNames := strings.Split(parts[4], ", ")
 			moves := []game.Move{}
 			for _, mn := range moveNames {
-				type_, power, _ := data.GetMoveTypeAndPower(mn)
-				moves = append(moves, game.Move{Name: mn, Type: type_, Power: power})
+				moves = append(moves, newMove(dex, mn))
 			}
 			if player, ok := state.Players[id]; ok {
 				if poke, ok := player.Team[pokeName]; ok {
@@ -64,7 +70,27 @@ func ProcessLine(state *game.BattleState, line string) {
 				}
 			}
 		}
-	case "switch":
+	case "teamsize":
+		if len(parts) >= 4 {
+			if player, ok := state.Players[parts[2]]; ok {
+				player.TeamSize, _ = strconv.Atoi(parts[3])
+			}
+		}
+	case "win":
+		if len(parts) >= 3 {
+			for _, player := range state.Players {
+				if player.Name == parts[2] {
+					state.Winner = player.ID
+				}
+			}
+		}
+	case "request":
+		processRequest(dex, state, strings.TrimPrefix(strings.TrimSpace(line), "|request|"))
+	case "tier":
+		if len(parts) >= 3 && state.Format == "" {
+			state.Format = data.ToID(parts[2])
+		}
+	case "switch", "drag":
 		if len(parts) >= 3 {
 			userInfo := strings.SplitN(parts[2], ": ", 2)
 			if len(userInfo) == 2 {
@@ -73,10 +99,43 @@ func ProcessLine(state *game.BattleState, line string) {
 				cleanName := strings.TrimSpace(pokeName)
 
 				if player, ok := state.Players[playerID]; ok {
-					player.Active = player.GetOrCreatePokemon(cleanName)
+					species := cleanName
+					if len(parts) >= 4 {
+						species, _, _ = parseDetails(parts[3])
+					}
+					if player.Active != nil {
+						// Los boosts y el contador de Toxic se pierden al
+						// salir del campo.
+						player.Active.Boosts = map[string]int{}
+						player.Active.SpeedBoosted = false
+						if player.Active.Status == "tox" {
+							player.Active.StatusTurns = 0
+						}
+					}
+					player.Active = player.GetOrCreatePokemonSpecies(cleanName, species)
+					if len(parts) >= 5 {
+						if hp, maxhp, ok := parseHP(parts[4]); ok {
+							player.Active.HP = hp
+							player.Active.MaxHP = maxhp
+						}
+					}
+					if len(parts) >= 4 {
+						species, level, tera := parseDetails(parts[3])
+						player.Active.Species = species
+						player.Active.Level = level
+						if tera != "" {
+							player.Active.TeraType = tera
+							player.Active.Terastallized = true
+						}
+					}
 
-					types := data.GetPokemonTypes(cleanName)
+					types := dex.GetPokemonTypes(species)
 					if len(types) > 0 {
+						player.Active.BaseTypes = types
+					}
+					if player.Active.Terastallized && player.Active.TeraType != "Stellar" {
+						player.Active.Type = []string{player.Active.TeraType}
+					} else if len(types) > 0 {
 						player.Active.Type = types
 						log.Printf("[Parser] %s (%s) tipos cargados: %v", playerID, cleanName, types)
 					} else {
@@ -93,30 +152,52 @@ func ProcessLine(state *game.BattleState, line string) {
 			if len(userInfo) == 2 {
 				playerID := string(userInfo[0][:2])
 				moveName := parts[3]
-				type_, power, _ := data.GetMoveTypeAndPower(moveName)
-				move := game.Move{Name: moveName, Type: type_, Power: power}
+				move := newMove(dex, moveName)
 				if player, ok := state.Players[playerID]; ok {
 					if player.Active == nil {
 						pokeName := userInfo[1]
 						player.Active = player.GetOrCreatePokemon(pokeName)
 					}
+					if !strings.Contains(line, "[from]") {
+						event := game.MoveEvent{
+							Player: playerID, Pokemon: player.Active, Move: moveName,
+							SpeedMultiplier: speed.Multiplier(dex, state, player, player.Active),
+							TrickRoom:       speed.TrickRoom(state),
+							OrderAltered:    speed.AltersOrder(dex, player.Active, moveName),
+							SpeedUnmodeled:  speed.Unmodeled(dex, state, player.Active),
+						}
+						player.Active.PriorityBoosted = false
+						if len(parts) >= 5 && len(parts[4]) >= 2 {
+							event.TargetPlayer = parts[4][:2]
+							event.Target = lookupPokemon(state, parts[4])
+						}
+						state.TurnMoves = append(state.TurnMoves, event)
+					}
 					if player.Active != nil {
-						exists := false
-						for _, m := range player.Active.Moves {
+						idx := -1
+						for i, m := range player.Active.Moves {
 							if m.Name == move.Name {
-								exists = true
+								idx = i
 								break
 							}
 						}
-						if !exists {
+						if idx < 0 {
 							player.Active.Moves = append(player.Active.Moves, move)
+							idx = len(player.Active.Moves) - 1
 							log.Printf("[Parser] %s (%s) aprende movimiento: %s", playerID, player.Active.Name, move.Name)
 						}
+						if !strings.Contains(line, "[from]") && player.Request == nil {
+							cost := 1
+							if len(parts) >= 5 && hasPressure(dex, state, playerID, parts[4]) {
+								cost = 2
+							}
+							player.Active.Moves[idx].PPUsed += cost
+						}
 					}
 				}
 			}
 		}
-	case "damage":
+	case "damage", "-damage", "-heal":
 		if len(parts) >= 4 {
 			pokeInfo := strings.SplitN(parts[2], ": ", 2)
 			if len(pokeInfo) == 2 {
@@ -124,17 +205,24 @@ func ProcessLine(state *game.BattleState, line string) {
 				pokeName := pokeInfo[1]
 				if player, ok := state.Players[playerID]; ok {
 					if poke, ok := player.Team[pokeName]; ok {
-						hpInfo := strings.Split(parts[3], "/")
-						if len(hpInfo) == 2 {
-							hp, _ := strconv.Atoi(strings.TrimSpace(hpInfo[0]))
-							maxhp, _ := strconv.Atoi(strings.TrimSpace(hpInfo[1]))
+						before, hadHP := poke.HP, poke.MaxHP > 0
+						if hp, maxhp, ok := parseHP(parts[3]); ok {
 							poke.HP = hp
-							poke.MaxHP = maxhp
+							if maxhp > 0 {
+								poke.MaxHP = maxhp
+							}
+						}
+						if parts[1] == "-damage" && hadHP && !strings.Contains(line, "[from]") {
+							observeDamage(dex, state, poke, before)
 						}
 					}
 				}
 			}
 		}
+	case "-crit":
+		if n := len(state.TurnMoves); n > 0 {
+			state.TurnMoves[n-1].Crit = true
+		}
 	case "faint":
 		if len(parts) >= 3 {
 			pokeInfo := strings.SplitN(parts[2], ": ", 2)
@@ -152,7 +240,16 @@ func ProcessLine(state *game.BattleState, line string) {
 		if len(parts) >= 3 {
 			t, err := strconv.Atoi(parts[2])
 			if err == nil {
+				speed.Observe(dex, state)
+				for _, player := range state.Players {
+					if player.Active != nil && player.Active.Status != "" {
+						player.Active.StatusTurns++
+					}
+				}
+				state.TurnMoves = nil
 				state.Turn = t
+				state.WinHistory = append(state.WinHistory, winprob.Probability(dex, state))
+				state.Snapshots = append(state.Snapshots, state.Snapshot())
 			}
 		}
 	case "-status":
@@ -165,6 +262,7 @@ func ProcessLine(state *game.BattleState, line string) {
 				if player, ok := state.Players[playerID]; ok {
 					if poke, ok := player.Team[pokeName]; ok {
 						poke.Status = status
+						poke.StatusTurns = 0
 					}
 				}
 			}
@@ -236,6 +334,13 @@ func ProcessLine(state *game.BattleState, line string) {
 				}
 			}
 		}
+	case "-start", "-end":
+		// Protosynthesis y Quark Drive anuncian qué stat suben.
+		if len(parts) >= 4 && (strings.HasSuffix(parts[3], "protosynthesisspe") || strings.HasSuffix(parts[3], "quarkdrivespe")) {
+			if poke := lookupPokemon(state, parts[2]); poke != nil {
+				poke.SpeedBoosted = parts[1] == "-start"
+			}
+		}
 	case "-weather":
 		if len(parts) >= 3 {
 			state.Weather = parts[2]
@@ -250,6 +355,85 @@ func ProcessLine(state *game.BattleState, line string) {
 			effect := parts[2]
 			delete(state.FieldEffects, effect)
 		}
+	case "-item", "-enditem":
+		if len(parts) >= 4 {
+			if poke := lookupPokemon(state, parts[2]); poke != nil {
+				if parts[1] == "-item" {
+					poke.Item = parts[3]
+				} else {
+					poke.Item = ""
+					if parts[3] == "Custap Berry" {
+						poke.PriorityBoosted = true
+					}
+				}
+			}
+		}
+	case "-terastallize":
+		if len(parts) >= 4 {
+			if poke := lookupPokemon(state, parts[2]); poke != nil {
+				poke.TeraType = parts[3]
+				poke.Terastallized = true
+				if len(poke.BaseTypes) == 0 {
+					poke.BaseTypes = poke.Type
+				}
+				if parts[3] != "Stellar" {
+					poke.Type = []string{parts[3]}
+				}
+			}
+		}
+	case "-sidestart", "-sideend":
+		if len(parts) >= 4 && len(parts[2]) >= 2 {
+			if player, ok := state.Players[parts[2][:2]]; ok {
+				if player.SideConditions == nil {
+					player.SideConditions = make(map[string]int)
+				}
+				condition := strings.TrimPrefix(parts[3], "move: ")
+				if parts[1] == "-sidestart" {
+					player.SideConditions[condition]++
+				} else {
+					delete(player.SideConditions, condition)
+				}
+			}
+		}
+	case "-activate":
+		if len(parts) == 4 {
+			poke := lookupPokemon(state, parts[2])
+			switch {
+			case poke == nil:
+			case parts[3] == "item: Quick Claw":
+				poke.Item = "Quick Claw"
+				poke.PriorityBoosted = true
+			case parts[3] == "ability: Quick Draw":
+				poke.Ability = "Quick Draw"
+				poke.PriorityBoosted = true
+			}
+		}
+		if len(parts) >= 5 {
+			poke := lookupPokemon(state, parts[2])
+			if poke == nil {
+				break
+			}
+			switch parts[3] {
+			case "move: Spite", "move: Eerie Spell":
+				amount := 4
+				if len(parts) >= 6 {
+					if n, err := strconv.Atoi(parts[5]); err == nil {
+						amount = n
+					}
+				}
+				for i := range poke.Moves {
+					if poke.Moves[i].Name == parts[4] {
+						poke.Moves[i].PPUsed += amount
+					}
+				}
+			case "item: Leppa Berry", "move: Leppa Berry":
+				for i := range poke.Moves {
+					if poke.Moves[i].Name == parts[4] {
+						poke.Moves[i].PPUsed = max(0, poke.Moves[i].PPUsed-10)
+					}
+				}
+			}
+		}
 	case "-ability":
 		if len(parts) >= 4 {
 			pokeInfo := strings.SplitN(parts[2], ": ", 2)
@@ -266,3 +450,114 @@ func ProcessLine(state *game.BattleState, line string) {
 		}
 	}
 }
+
+// parseDetails separa el campo de detalles de |switch| y |poke|, por ejemplo
+// "Garchomp, L84, F, tera:Fire".
+func parseDetails(details string) (species string, level int, tera string) {
+	fields := strings.Split(details, ",")
+	species = strings.TrimSpace(fields[0])
+	level = 100
+	for _, f := range fields[1:] {
+		f = strings.TrimSpace(f)
+		switch {
+		case strings.HasPrefix(f, "L"):
+			if l, err := strconv.Atoi(f[1:]); err == nil {
+				level = l
+			}
+		case strings.HasPrefix(f, "tera:"):
+			tera = f[len("tera:"):]
+		}
+	}
+	return species, level, tera
+}
+
+func lookupPokemon(state *game.BattleState, ident string) *game.Pokemon {
+	pokeInfo := strings.SplitN(ident, ": ", 2)
+	if len(pokeInfo) != 2 || len(pokeInfo[0]) < 2 {
+		return nil
+	}
+	player, ok := state.Players[pokeInfo[0][:2]]
+	if !ok {
+		return nil
+	}
+	return player.Team[pokeInfo[1]]
+}
+
+// parseHP interpreta "55/100", "55/100 par" o "0 fnt".
+func parseHP(field string) (int, int, bool) {
+	condition := strings.Fields(field)
+	if len(condition) == 0 {
+		return 0, 0, false
+	}
+	hpInfo := strings.Split(condition[0], "/")
+	hp, err := strconv.Atoi(hpInfo[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	maxhp := 0
+	if len(hpInfo) == 2 {
+		maxhp, _ = strconv.Atoi(hpInfo[1])
+	}
+	return hp, maxhp, true
+}
+
+// observeDamage pasa al estimador de stats el golpe directo que acaba de
+// recibir poke, si corresponde al último movimiento del turno.
+func observeDamage(dex *data.Dex, state *game.BattleState, poke *game.Pokemon, before int) {
+	n := len(state.TurnMoves)
+	if n == 0 || state.TurnMoves[n-1].Target != poke || poke.MaxHP <= 0 {
+		return
+	}
+	lost := float64(before - poke.HP)
+	obs := infer.DamageObservation{Event: state.TurnMoves[n-1]}
+	if poke.MaxHP == 100 {
+		// Sólo vemos porcentajes: cada extremo puede estar redondeado.
+		obs.MinFrac = (lost - 1) / 100
+		obs.MaxFrac = (lost + 1) / 100
+	} else {
+		obs.MinFrac = lost / float64(poke.MaxHP)
+		obs.MaxFrac = obs.MinFrac
+	}
+	if poke.HP == 0 {
+		obs.MaxFrac = math.Inf(1)
+	}
+	infer.ObserveDamage(dex, state, obs)
+}
+
+func newMove(dex *data.Dex, name string) game.Move {
+	move := game.Move{Name: name}
+	move.Type, move.Power, _ = dex.GetMoveTypeAndPower(name)
+	if m, ok := dex.GetMove(name); ok {
+		move.MaxPP = m.MaxPP
+	}
+	return move
+}
+
+// hasPressure indica si el objetivo ident es un rival de playerID con
+// Pressure, ya sea revelada o porque es la única habilidad de su especie.
+func hasPressure(dex *data.Dex, state *game.BattleState, playerID, ident string) bool {
+	if len(ident) < 2 || ident[:2] == playerID {
+		return false
+	}
+	target := lookupPokemon(state, ident)
+	if target == nil {
+		return false
+	}
+	if target.Ability != "" {
+		return target.Ability == "Pressure"
+	}
+	species := target.Species
+	if species == "" {
+		species = target.Name
+	}
+	abilities := dex.GetPokemonAbilities(species)
+	if len(abilities) == 0 {
+		return false
+	}
+	for _, a := range abilities {
+		if a != "Pressure" {
+			return false
+		}
+	}
+	return true
+}
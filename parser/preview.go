@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"fmt"
+	"showdown-analizer/analysis"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"strings"
+)
+
+func hasPreview(state *game.BattleState) bool {
+	for _, player := range state.Players {
+		for _, poke := range player.Team {
+			if poke.Preview {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func renderTeamPreview(dex *data.Dex, state *game.BattleState) string {
+	report := analysis.BuildTeamPreview(dex, state)
+	var sb strings.Builder
+	sb.WriteString("<div class='team-preview'><h3>Team preview</h3>")
+	for _, side := range report.Sides {
+		sb.WriteString(fmt.Sprintf("<h4>%s</h4>", escape(side.Name)))
+
+		sb.WriteString("<div><b>Velocidades:</b> ")
+		tiers := []string{}
+		for _, m := range side.Members {
+			switch {
+			case m.SpeedMax == 0:
+				tiers = append(tiers, escape(m.Name)+" ?")
+			case m.SpeedMin == m.SpeedMax:
+				tiers = append(tiers, fmt.Sprintf("%s %d", escape(m.Name), m.SpeedMax))
+			default:
+				tiers = append(tiers, fmt.Sprintf("%s %d–%d", escape(m.Name), m.SpeedMin, m.SpeedMax))
+			}
+		}
+		sb.WriteString(strings.Join(tiers, ", ") + "</div>")
+
+		sb.WriteString("<div><b>Leads probables:</b> " + escapeJoin(side.LikelyLeads, ", ") + "</div>")
+		if len(side.Unresisted) > 0 {
+			sb.WriteString("<div><b>Sin resistir:</b> " + escapeJoin(side.Unresisted, ", ") + "</div>")
+		}
+		sb.WriteString(renderMatrix(side.Matrix))
+	}
+	if report.RecommendedLead != "" {
+		sb.WriteString(fmt.Sprintf("<div class='suggestion-highlight'>Lead recomendado: <b>%s</b></div>", escape(report.RecommendedLead)))
+	}
+	sb.WriteString("</div>")
+	return sb.String()
+}
+
+// renderMatrix muestra sólo los tipos con debilidades o sin resistencias,
+// que son los que importan para elegir lead.
+func renderMatrix(rows []analysis.TypeRow) string {
+	var sb strings.Builder
+	sb.WriteString("<table class='type-matrix'><tr><th>Tipo</th><th>Débiles</th><th>Resisten</th><th>Inmunes</th></tr>")
+	for _, row := range rows {
+		if row.Weak == 0 && row.Resist+row.Immune > 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td></tr>", escape(row.Type), row.Weak, row.Resist, row.Immune))
+	}
+	sb.WriteString("</table>")
+	return sb.String()
+}
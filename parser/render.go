@@ -2,13 +2,34 @@ package parser
 
 import (
 	"fmt"
+	"html/template"
 	"log"
+	"math"
+	"showdown-analizer/data"
 	"showdown-analizer/game"
+	"showdown-analizer/infer"
+	"showdown-analizer/predict"
+	"showdown-analizer/speed"
 	"sort"
 	"strings"
 	"unicode"
 )
 
+// escape escapa texto que viene del log o de los datos (nombres de
+// jugadores, apodos, movimientos, objetos) antes de meterlo en el HTML.
+func escape(s string) string {
+	return template.HTMLEscapeString(s)
+}
+
+// escapeJoin escapa cada elemento y los une con sep.
+func escapeJoin(items []string, sep string) string {
+	escaped := make([]string, len(items))
+	for i, s := range items {
+		escaped[i] = escape(s)
+	}
+	return strings.Join(escaped, sep)
+}
+
 func capitalizeFirst(s string) string {
 	if len(s) == 0 {
 		return s
@@ -21,120 +42,74 @@ func capitalizeFirst(s string) string {
 	return string(runes)
 }
 
-var typeChart = map[string]map[string]float64{
-	"Fire": {
-		"Water": 0.5, "Rock": 0.5, "Fire": 0.5, "Grass": 2, "Ice": 2, "Bug": 2, "Steel": 2, "Dragon": 0.5,
-	},
-	"Flying": {
-		"Grass": 2, "Fighting": 2, "Bug": 2, "Electric": 0.5, "Rock": 0.5, "Steel": 0.5,
-	},
-	"Dragon": {
-		"Dragon": 2, "Steel": 0.5,
-	},
-	"Water": {
-		"Fire": 2, "Water": 0.5, "Grass": 0.5, "Ground": 2, "Rock": 2, "Dragon": 0.5,
-	},
-	"Dark": {
-		"Ghost": 2, "Psychic": 2, "Dark": 0.5, "Fighting": 0.5, "Fairy": 0.5,
-	},
-	"Rock": {
-		"Fire": 2, "Ice": 2, "Flying": 2, "Bug": 2, "Fighting": 0.5, "Ground": 0.5, "Steel": 0.5,
-	},
-	"Ice": {
-		"Dragon": 2, "Flying": 2, "Grass": 2, "Ground": 2, "Fire": 0.5, "Water": 0.5, "Ice": 0.5, "Steel": 0.5,
-	},
-	"Steel": {
-		"Rock": 2, "Ice": 2, "Fairy": 2, "Steel": 0.5, "Fire": 0.5, "Water": 0.5, "Electric": 0.5,
-	},
-	"Fighting": {
-		"Normal": 2, "Rock": 2, "Steel": 2, "Ice": 2, "Dark": 2, "Ghost": 0, "Poison": 0.5, "Flying": 0.5, "Psychic": 0.5, "Bug": 0.5, "Fairy": 0.5,
-	},
-	"Normal": {
-		"Rock": 0.5, "Ghost": 0, "Steel": 0.5,
-	},
-	"Electric": {
-		"Flying": 2, "Water": 2, "Ground": 0, "Grass": 0.5, "Electric": 0.5, "Dragon": 0.5,
-	},
-	"Grass": {
-		"Ground": 2, "Rock": 2, "Water": 2, "Flying": 0.5, "Poison": 0.5, "Bug": 0.5, "Steel": 0.5, "Fire": 0.5, "Grass": 0.5, "Dragon": 0.5,
-	},
-	"Psychic": {
-		"Fighting": 2, "Poison": 2, "Steel": 0.5, "Psychic": 0.5, "Dark": 0,
-	},
-	"Ghost": {
-		"Ghost": 2, "Psychic": 2, "Normal": 0, "Dark": 0.5,
-	},
-	"Poison": {
-		"Grass": 2, "Fairy": 2, "Poison": 0.5, "Ground": 0.5, "Rock": 0.5, "Ghost": 0.5, "Steel": 0,
-	},
-	"Ground": {
-		"Poison": 2, "Rock": 2, "Steel": 2, "Fire": 2, "Electric": 2, "Flying": 0, "Bug": 0.5, "Grass": 0.5,
-	},
-	"Bug": {
-		"Grass": 2, "Psychic": 2, "Dark": 2, "Fighting": 0.5, "Flying": 0.5, "Poison": 0.5, "Ghost": 0.5, "Steel": 0.5, "Fire": 0.5, "Fairy": 0.5,
-	},
-	"Fairy": {
-		"Fighting": 2, "Dragon": 2, "Dark": 2, "Poison": 0.5, "Steel": 0.5, "Fire": 0.5,
-	},
-}
-
-func getTypeEffectiveness(moveType string, targetTypes []string) float64 {
-	eff := 1.0
-	for _, t := range targetTypes {
-		if m, ok := typeChart[moveType]; ok {
-			if v, ok := m[t]; ok {
-				eff *= v
-			}
-		}
-	}
-	return eff
+func getTypeEffectiveness(dex *data.Dex, move game.Move, attacker *game.Pokemon, target *game.Pokemon) float64 {
+	return dex.Effectiveness(move.Type, target.Type, data.EffectivenessOptions{
+		MoveID:  data.ToID(move.Name),
+		Scrappy: attacker != nil && (attacker.Ability == "Scrappy" || attacker.Ability == "Mind's Eye"),
+	})
 }
 
-func getWeaknesses(pokemonTypes []string) []string {
-	weaknesses := make(map[string]bool)
-	
-	for attackType, effectiveness := range typeChart {
-		for _, defenseType := range pokemonTypes {
-			if eff, exists := effectiveness[defenseType]; exists && eff > 1 {
-				weaknesses[attackType] = true
-			}
-		}
-	}
-	
+func getWeaknesses(dex *data.Dex, pokemonTypes []string) []string {
 	var result []string
-	for weakness := range weaknesses {
-		result = append(result, weakness)
+	for _, attackType := range dex.GetTypeNames() {
+		if dex.Effectiveness(attackType, pokemonTypes, data.EffectivenessOptions{}) > 1 {
+			result = append(result, attackType)
+		}
 	}
-	sort.Strings(result)
 	return result
 }
 
-func getSuggestions(player *game.Player, opponent *game.Pokemon) string {
-	if player.Active == nil || len(player.Active.Moves) == 0 {
+func getSuggestions(dex *data.Dex, state *game.BattleState, player *game.Player, opponent *game.Pokemon) string {
+	if player.Active == nil {
 		return "<i>Sin movimientos conocidos aún.</i>"
 	}
-	
+
 	type moveScore struct {
 		move  game.Move
 		score float64
 		eff   float64
+		prob  float64
 	}
-	
+
 	var scored []moveScore
 	for _, move := range player.Active.Moves {
+		if player.DisabledMove(move.Name) || move.PP() == 0 {
+			continue
+		}
 		power := move.Power
 		if power == 0 {
 			power = 80
 		}
-		eff := getTypeEffectiveness(move.Type, opponent.Type)
+		eff := getTypeEffectiveness(dex, move, player.Active, opponent)
 		score := float64(power) * eff
-		scored = append(scored, moveScore{move, score, eff})
+		scored = append(scored, moveScore{move, score, eff, 1})
+	}
+
+	if len(player.Active.Moves) < 4 {
+		if pred := predict.For(dex, state.Format, player.Active); pred != nil {
+			for _, c := range pred.Moves {
+				if c.Prob < minPredictedMoveProb {
+					continue
+				}
+				type_, power, _ := dex.GetMoveTypeAndPower(c.Name)
+				move := game.Move{Name: c.Name, Type: type_, Power: power}
+				if power == 0 {
+					power = 80
+				}
+				eff := getTypeEffectiveness(dex, move, player.Active, opponent)
+				scored = append(scored, moveScore{move, float64(power) * eff * c.Prob, eff, c.Prob})
+			}
+		}
+	}
+
+	if len(scored) == 0 {
+		return "<i>Sin movimientos conocidos aún.</i>"
 	}
-	
-	sort.Slice(scored, func(i, j int) bool { 
-		return scored[i].score > scored[j].score 
+
+	sort.Slice(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
 	})
-	
+
 	var result strings.Builder
 	result.WriteString("Movimientos conocidos:<br>")
 	for i, ms := range scored {
@@ -144,15 +119,27 @@ func getSuggestions(player *game.Player, opponent *game.Pokemon) string {
 		} else if ms.eff < 1 {
 			effText = " (No muy efectivo)"
 		}
-		
-		result.WriteString(fmt.Sprintf("%d. <b>%s</b> [%s] - %.0f pts%s<br>", 
-			i+1, ms.move.Name, ms.move.Type, ms.score, effText))
+		predicted := ""
+		if ms.prob < 1 {
+			predicted = fmt.Sprintf(" <i>(predicho, %.0f%%)</i>", ms.prob*100)
+		}
+
+		damage := ""
+		if lo, hi, ok := infer.EstimateDamage(dex, state, player.Active, opponent, ms.move.Name); ok {
+			damage = fmt.Sprintf(" ≈ %.0f–%.0f%%", lo*100, math.Min(hi*100, 999))
+		}
+
+		result.WriteString(fmt.Sprintf("%d. <b>%s</b> [%s] - %.0f pts%s%s%s<br>",
+			i+1, escape(ms.move.Name), escape(ms.move.Type), ms.score, damage, effText, predicted))
 	}
-	
+
 	return result.String()
 }
 
-func bestMove(p1 *game.Pokemon, p2 *game.Pokemon) (game.Move, float64) {
+// Por debajo de esta probabilidad un movimiento predicho no se sugiere.
+const minPredictedMoveProb = 0.3
+
+func bestMove(dex *data.Dex, p1 *game.Pokemon, p2 *game.Pokemon) (game.Move, float64) {
 	best := game.Move{}
 	bestScore := -1.0
 	for _, move := range p1.Moves {
@@ -160,7 +147,7 @@ func bestMove(p1 *game.Pokemon, p2 *game.Pokemon) (game.Move, float64) {
 		if power == 0 {
 			power = 80
 		}
-		eff := getTypeEffectiveness(move.Type, p2.Type)
+		eff := getTypeEffectiveness(dex, move, p1, p2)
 		score := float64(power) * eff
 		if score > bestScore {
 			best = move
@@ -170,35 +157,7 @@ func bestMove(p1 *game.Pokemon, p2 *game.Pokemon) (game.Move, float64) {
 	return best, bestScore
 }
 
-func bestSwitch(p1 *game.Player, p2 *game.Pokemon) *game.Pokemon {
-	var best *game.Pokemon
-	bestScore := 0.0
-	for _, poke := range p1.Team {
-		if poke == p1.Active || poke.Fainted {
-			continue
-		}
-		score := 1.0
-		for _, t := range p2.Type {
-			for _, myType := range poke.Type {
-				if m, ok := typeChart[t]; ok {
-					if v, ok := m[myType]; ok {
-						score *= v
-					}
-				}
-			}
-		}
-		if best == nil || score < bestScore {
-			best = poke
-			bestScore = score
-		}
-	}
-	if bestScore < 1.0 {
-		return best
-	}
-	return nil
-}
-
-func bestMovesList(p2 *game.Pokemon, p1 *game.Pokemon) []game.Move {
+func bestMovesList(dex *data.Dex, p2 *game.Pokemon, p1 *game.Pokemon) []game.Move {
 	type moveScore struct {
 		move  game.Move
 		score float64
@@ -209,7 +168,7 @@ func bestMovesList(p2 *game.Pokemon, p1 *game.Pokemon) []game.Move {
 		if power == 0 {
 			power = 80
 		}
-		eff := getTypeEffectiveness(move.Type, p1.Type)
+		eff := getTypeEffectiveness(dex, move, p2, p1)
 		score := float64(power) * eff
 		scored = append(scored, moveScore{move, score})
 	}
@@ -221,13 +180,13 @@ func bestMovesList(p2 *game.Pokemon, p1 *game.Pokemon) []game.Move {
 	return res
 }
 
-func RenderBattleState(state *game.BattleState) string {
+func RenderBattleState(dex *data.Dex, state *game.BattleState) string {
 	var sb strings.Builder
 
 	sb.WriteString("<div class='battle-summary'>")
 
 	if state.Weather != "" {
-		sb.WriteString(fmt.Sprintf("<div><b>Clima:</b> %s</div>", state.Weather))
+		sb.WriteString(fmt.Sprintf("<div><b>Clima:</b> %s</div>", escape(state.Weather)))
 	}
 	if len(state.FieldEffects) > 0 {
 		effects := make([]string, 0, len(state.FieldEffects))
@@ -235,21 +194,35 @@ func RenderBattleState(state *game.BattleState) string {
 			effects = append(effects, eff)
 		}
 		sort.Strings(effects)
-		sb.WriteString("<div><b>Campo:</b> " + strings.Join(effects, ", ") + "</div>")
+		sb.WriteString("<div><b>Campo:</b> " + escapeJoin(effects, ", ") + "</div>")
+	}
+
+	if state.Turn == 0 && hasPreview(state) {
+		sb.WriteString(renderTeamPreview(dex, state))
 	}
 
 	sb.WriteString(fmt.Sprintf("<h3>Turno: %d</h3>", state.Turn))
+	sb.WriteString(renderWinProbability(state))
 
 	p1 := state.Players["p1"]
 	p2 := state.Players["p2"]
 
-	for _, player := range state.Players {
-		sb.WriteString(fmt.Sprintf("<h4>%s</h4>", player.Name))
+	for _, player := range state.OrderedPlayers() {
+		side := ""
+		switch player {
+		case state.Self():
+			side = " <span style='color:#68d391;'>(vos)</span>"
+		case state.Opponent():
+			side = " <span style='color:#ff6b6b;'>(rival)</span>"
+		}
+		sb.WriteString(fmt.Sprintf("<h4>%s%s</h4>", escape(player.Name), side))
 		if player.Active != nil {
 			poke := player.Active
 			ps := "?/?"
-			if poke.MaxHP > 0 {
-				ps = fmt.Sprintf("%d/%d", poke.HP, poke.MaxHP)
+			if poke.MaxHP == 100 {
+				ps = fmt.Sprintf("%d%%", poke.HP)
+			} else if poke.MaxHP > 0 {
+				ps = fmt.Sprintf("%d/%d (%d%%)", poke.HP, poke.MaxHP, poke.HP*100/poke.MaxHP)
 			}
 			fainted := ""
 			if poke.Fainted {
@@ -257,27 +230,27 @@ func RenderBattleState(state *game.BattleState) string {
 			}
 			status := ""
 			if poke.Status != "" {
-				status = fmt.Sprintf("<span style='color:#f1c40f;'>[%s]</span>", poke.Status)
+				status = fmt.Sprintf("<span style='color:#f1c40f;'>[%s]</span>", escape(poke.Status))
 			}
 			ability := ""
 			if poke.Ability != "" {
-				ability = fmt.Sprintf("<span style='color:#7ed6df;'>%s</span>", poke.Ability)
+				ability = fmt.Sprintf("<span style='color:#7ed6df;'>%s</span>", escape(poke.Ability))
 			}
-			
+
 			typeStr := ""
 			if len(poke.Type) > 0 {
-				typeStr = fmt.Sprintf(" <span style='color:#9b9b9b;'>(%s)</span>", strings.Join(poke.Type, "/"))
+				typeStr = fmt.Sprintf(" <span style='color:#9b9b9b;'>(%s)</span>", escapeJoin(poke.Type, "/"))
 			}
-			
-			sb.WriteString(fmt.Sprintf("<b>%s</b>%s %s %s <span style='color:#aaa;'>[%s]</span> %s<br>", poke.Name, typeStr, fainted, status, ps, ability))
-			
+
+			sb.WriteString(fmt.Sprintf("<b>%s</b>%s %s %s <span style='color:#aaa;'>[%s]</span> %s<br>", escape(poke.Name), typeStr, fainted, status, ps, ability))
+
 			if len(poke.Type) > 0 {
-				weaknesses := getWeaknesses(poke.Type)
+				weaknesses := getWeaknesses(dex, poke.Type)
 				if len(weaknesses) > 0 {
-					sb.WriteString(fmt.Sprintf("<span style='color:#ff6b6b;'>Débil a: %s</span><br>", strings.Join(weaknesses, ", ")))
+					sb.WriteString(fmt.Sprintf("<span style='color:#ff6b6b;'>Débil a: %s</span><br>", escapeJoin(weaknesses, ", ")))
 				}
 			}
-			
+
 			if len(poke.Boosts) > 0 {
 				boosts := make([]string, 0, len(poke.Boosts))
 				for stat, val := range poke.Boosts {
@@ -286,7 +259,7 @@ func RenderBattleState(state *game.BattleState) string {
 						if val < 0 {
 							prefix = ""
 						}
-						boosts = append(boosts, fmt.Sprintf("%s%d %s", prefix, val, capitalizeFirst(stat)))
+						boosts = append(boosts, fmt.Sprintf("%s%d %s", prefix, val, escape(capitalizeFirst(stat))))
 					}
 				}
 				if len(boosts) > 0 {
@@ -297,11 +270,24 @@ func RenderBattleState(state *game.BattleState) string {
 				sb.WriteString("Movimientos vistos: ")
 				moveNames := []string{}
 				for _, m := range poke.Moves {
-					moveNames = append(moveNames, m.Name)
+					if pp := m.PP(); pp >= 0 {
+						moveNames = append(moveNames, fmt.Sprintf("%s (%d/%d PP)", escape(m.Name), pp, m.MaxPP))
+					} else {
+						moveNames = append(moveNames, escape(m.Name))
+					}
 				}
 				sb.WriteString(strings.Join(moveNames, ", "))
 				sb.WriteString("<br>")
+				sb.WriteString(renderPPWarnings(dex, poke))
+			}
+			sb.WriteString(renderStatRanges(poke))
+			if r := speed.Effective(dex, state, player); r.Known() {
+				sb.WriteString(renderSpeed(r, poke))
 			}
+			sb.WriteString(renderPrediction(predict.For(dex, state.Format, poke)))
+		}
+		if state.Turn > 0 && len(player.Team) > 0 {
+			sb.WriteString(renderTeamReport(dex, state, player))
 		}
 	}
 
@@ -310,15 +296,133 @@ func RenderBattleState(state *game.BattleState) string {
 	}
 
 	if p1 != nil && p2 != nil && p1.Active != nil && p2.Active != nil {
-		sb.WriteString("<div class='suggestion'><b>Sugerencias para " + p1.Name + ":</b><br>")
-		sb.WriteString(getSuggestions(p1, p2.Active))
-		sb.WriteString("</div>")
-		
-		sb.WriteString("<div class='suggestion'><b>Sugerencias para " + p2.Name + ":</b><br>")
-		sb.WriteString(getSuggestions(p2, p1.Active))
+		sb.WriteString("<div class='speed-order'><b>Mueve primero:</b> ")
+		if first := speed.MovesFirst(dex, state, p1, p2); first != nil {
+			sb.WriteString(fmt.Sprintf("%s (%s)", escape(first.Active.Name), escape(first.Name)))
+		} else {
+			sb.WriteString("incierto")
+		}
+		if speed.TrickRoom(state) {
+			sb.WriteString(" <i>(Trick Room)</i>")
+		}
 		sb.WriteString("</div>")
+
+		if self, opponent := state.Self(), state.Opponent(); self != nil && opponent != nil && opponent.Active != nil {
+			sb.WriteString("<div class='suggestion'><b>Sugerencias para " + escape(self.Name) + ":</b><br>")
+			sb.WriteString(getSuggestions(dex, state, self, opponent.Active))
+			sb.WriteString(renderSwitches(dex, state, self, opponent))
+			sb.WriteString(renderSearch(state, self))
+			sb.WriteString("</div>")
+		} else {
+			sb.WriteString("<div class='suggestion'><b>Sugerencias para " + escape(p1.Name) + ":</b><br>")
+			sb.WriteString(getSuggestions(dex, state, p1, p2.Active))
+			sb.WriteString(renderSwitches(dex, state, p1, p2))
+			sb.WriteString(renderSearch(state, p1))
+			sb.WriteString("</div>")
+
+			sb.WriteString("<div class='suggestion'><b>Sugerencias para " + escape(p2.Name) + ":</b><br>")
+			sb.WriteString(getSuggestions(dex, state, p2, p1.Active))
+			sb.WriteString(renderSwitches(dex, state, p2, p1))
+			sb.WriteString(renderSearch(state, p2))
+			sb.WriteString("</div>")
+		}
 	}
 
 	sb.WriteString("</div>")
 	return sb.String()
 }
+
+func renderPrediction(pred *predict.Prediction) string {
+	if pred == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("<div class='prediction'>")
+	sb.WriteString(fmt.Sprintf("<span style='color:#9b9b9b;'>Predicción (%s", escape(pred.Source)))
+	if pred.Level > 0 {
+		sb.WriteString(fmt.Sprintf(", nivel %d", pred.Level))
+	}
+	sb.WriteString(")</span><br>")
+	writeChances(&sb, "Posibles movimientos", pred.Moves, 6)
+	writeChances(&sb, "Habilidad", pred.Abilities, 3)
+	writeChances(&sb, "Objeto", pred.Items, 3)
+	writeChances(&sb, "Tera", pred.TeraTypes, 3)
+	writeChances(&sb, "Rol", pred.Roles, 3)
+	writeChances(&sb, "Spreads", pred.Spreads, 3)
+	sb.WriteString("</div>")
+	return sb.String()
+}
+
+func writeChances(sb *strings.Builder, label string, chances []predict.Chance, limit int) {
+	if len(chances) == 0 {
+		return
+	}
+	parts := []string{}
+	for i, c := range chances {
+		if i >= limit {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s (%.0f%%)", escape(c.Name), c.Prob*100))
+	}
+	sb.WriteString(fmt.Sprintf("%s: %s<br>", label, strings.Join(parts, ", ")))
+}
+
+func renderSpeed(r speed.Range, poke *game.Pokemon) string {
+	value := fmt.Sprintf("%d", r.Min)
+	if !r.Exact() {
+		value = fmt.Sprintf("%d–%d", r.Min, r.Max)
+	}
+	scarf := ""
+	if poke.ScarfSuspected {
+		scarf = " <b>¿Choice Scarf?</b>"
+	}
+	return fmt.Sprintf("<span style='color:#74b9ff;'>Velocidad: %s%s</span><br>", value, scarf)
+}
+
+var statLabels = []struct{ id, label string }{
+	{"hp", "HP"}, {"atk", "Atk"}, {"def", "Def"}, {"spa", "SpA"}, {"spd", "SpD"},
+}
+
+func renderStatRanges(poke *game.Pokemon) string {
+	parts := []string{}
+	for _, s := range statLabels {
+		r, ok := poke.StatRanges[s.id]
+		if !ok {
+			continue
+		}
+		if r.Min == r.Max {
+			parts = append(parts, fmt.Sprintf("%s %d", s.label, r.Min))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s %d–%d", s.label, r.Min, r.Max))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "<span style='color:#a29bfe;'>Stats estimados: " + strings.Join(parts, ", ") + "</span><br>"
+}
+
+// renderPPWarnings avisa cuando un movimiento está agotado o casi, con
+// especial atención a los de recuperación, que deciden las guerras de PP.
+func renderPPWarnings(dex *data.Dex, poke *game.Pokemon) string {
+	var warnings []string
+	for _, m := range poke.Moves {
+		pp := m.PP()
+		if pp < 0 {
+			continue
+		}
+		md, _ := dex.GetMove(m.Name)
+		switch {
+		case pp == 0 && md.Heal:
+			warnings = append(warnings, fmt.Sprintf("¡Sin PP de recuperación (%s)!", escape(m.Name)))
+		case pp == 0:
+			warnings = append(warnings, fmt.Sprintf("%s sin PP", escape(m.Name)))
+		case pp <= 3 && md.Heal:
+			warnings = append(warnings, fmt.Sprintf("Quedan %d PP de %s", pp, escape(m.Name)))
+		}
+	}
+	if len(warnings) == 0 {
+		return ""
+	}
+	return "<span style='color:#fd79a8;'>" + strings.Join(warnings, " · ") + "</span><br>"
+}
@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"showdown-analizer/data/datatest"
+	"showdown-analizer/game"
+	"strings"
+	"testing"
+)
+
+func TestRenderBattleStateEscapesNames(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	state := game.NewBattleState()
+	for _, line := range []string{
+		"|player|p1|<script>alert(1)</script>|1",
+		"|player|p2|Bob|2",
+		"|gen|9",
+		"|switch|p1a: <img src=x onerror=alert(1)>|Garchomp, M|100/100",
+		"|switch|p2a: Heatran|Heatran, F|100/100",
+		"|turn|1",
+	} {
+		ProcessLine(dex, state, line)
+	}
+
+	html := RenderBattleState(dex, state)
+	for _, raw := range []string{"<script>", "<img"} {
+		if strings.Contains(html, raw) {
+			t.Errorf("el resumen contiene %q sin escapar", raw)
+		}
+	}
+	for _, escaped := range []string{"&lt;script&gt;", "&lt;img"} {
+		if !strings.Contains(html, escaped) {
+			t.Errorf("falta %q: el nombre no aparece escapado", escaped)
+		}
+	}
+}
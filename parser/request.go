@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"encoding/json"
+	"log"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"strings"
+)
+
+// processRequest vuelca el |request| en el jugador correspondiente: equipo
+// completo con stats, movimientos, objeto, habilidad y tipo Tera exactos.
+func processRequest(dex *data.Dex, state *game.BattleState, raw string) {
+	if strings.TrimSpace(raw) == "" {
+		return
+	}
+	var req game.Request
+	if err := json.Unmarshal([]byte(raw), &req); err != nil {
+		log.Printf("[Parser] Error decodificando |request|: %v", err)
+		return
+	}
+	if req.Side.ID == "" {
+		return
+	}
+
+	player, ok := state.Players[req.Side.ID]
+	if !ok {
+		player = &game.Player{ID: req.Side.ID, Name: req.Side.Name, Team: make(map[string]*game.Pokemon)}
+		state.Players[req.Side.ID] = player
+	}
+	player.Request = &req
+	if state.Perspective == "" {
+		state.Perspective = req.Side.ID
+		log.Printf("[Parser] |request| recibido: analizando desde %s", req.Side.ID)
+	}
+
+	for _, rp := range req.Side.Pokemon {
+		_, name, found := strings.Cut(rp.Ident, ": ")
+		if !found {
+			continue
+		}
+		species, level, _ := parseDetails(rp.Details)
+		poke := player.GetOrCreatePokemonSpecies(name, species)
+		poke.Species, poke.Level = species, level
+		if hp, maxhp, ok := parseHP(rp.Condition); ok {
+			poke.HP = hp
+			poke.MaxHP = maxhp
+		}
+		poke.Fainted = strings.HasSuffix(rp.Condition, " fnt")
+		poke.Status = ""
+		if fields := strings.Fields(rp.Condition); len(fields) == 2 && fields[1] != "fnt" {
+			poke.Status = fields[1]
+		}
+		poke.Stats = rp.Stats
+		poke.Item = rp.Item
+		if rp.Ability != "" {
+			poke.Ability = dex.GetAbilityName(rp.Ability)
+		}
+		poke.TeraType = rp.TeraType
+		poke.Terastallized = rp.Terastallized != ""
+		if types := dex.GetPokemonTypes(poke.Species); len(types) > 0 {
+			poke.BaseTypes = types
+			if !poke.Terastallized {
+				poke.Type = types
+			}
+		}
+
+		moves := make([]game.Move, 0, len(rp.Moves))
+		for _, id := range rp.Moves {
+			move := newMove(dex, dex.GetMoveName(id))
+			if rp.Active && len(req.Active) > 0 {
+				for _, am := range req.Active[0].Moves {
+					if am.ID == id && am.MaxPP > 0 {
+						move.MaxPP = am.MaxPP
+						move.PPUsed = am.MaxPP - am.PP
+					}
+				}
+			} else if old := findMove(poke.Moves, move.Name); old != nil {
+				move.MaxPP, move.PPUsed = old.MaxPP, old.PPUsed
+			}
+			moves = append(moves, move)
+		}
+		poke.Moves = moves
+
+		if rp.Active {
+			player.Active = poke
+		}
+	}
+}
+
+func findMove(moves []game.Move, name string) *game.Move {
+	for i := range moves {
+		if moves[i].Name == name {
+			return &moves[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"fmt"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/search"
+	"strings"
+)
+
+// Elecciones que se guardan y muestran como máximo.
+const maxSearchResults = 3
+
+// SearchTurn corre la búsqueda para los jugadores que muestra el resumen:
+// Self, o ambos como espectador. Cuesta DefaultOptions.Budget por jugador,
+// así que se llama una vez por turno sobre una copia del estado y el
+// resultado se guarda con SetSearches.
+func SearchTurn(dex *data.Dex, state *game.BattleState) map[string]game.SearchSummary {
+	p1, p2 := state.Players["p1"], state.Players["p2"]
+	if p1 == nil || p2 == nil || p1.Active == nil || p2.Active == nil {
+		return nil
+	}
+	pairs := [][2]*game.Player{{p1, p2}, {p2, p1}}
+	if self, opponent := state.Self(), state.Opponent(); self != nil && opponent != nil {
+		pairs = [][2]*game.Player{{self, opponent}}
+	}
+
+	searches := map[string]game.SearchSummary{}
+	for _, pair := range pairs {
+		report := search.Search(dex, state, pair[0], pair[1], search.DefaultOptions)
+		summary := game.SearchSummary{Turn: state.Turn, Depth: report.Depth, Samples: report.Samples}
+		for i, r := range report.Results {
+			if i == maxSearchResults {
+				break
+			}
+			summary.Choices = append(summary.Choices, game.RankedChoice{Choice: r.Choice.String(), Value: r.Value})
+		}
+		searches[pair[0].ID] = summary
+	}
+	return searches
+}
+
+// renderSearch muestra la búsqueda ya calculada de player para este turno;
+// nunca la corre.
+func renderSearch(state *game.BattleState, player *game.Player) string {
+	summary, ok := state.Searches[player.ID]
+	if !ok || summary.Turn != state.Turn || len(summary.Choices) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<br>Búsqueda (%d turnos, %d muestras):<br>", summary.Depth, summary.Samples))
+	for i, c := range summary.Choices {
+		sb.WriteString(fmt.Sprintf("%d. <b>%s</b> - valor %+.0f<br>", i+1, escape(c.Choice), c.Value*100))
+	}
+	return sb.String()
+}
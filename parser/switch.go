@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"fmt"
+	"showdown-analizer/analysis"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"strings"
+)
+
+// Cambios que se muestran como máximo.
+const maxSwitchSuggestions = 3
+
+func renderSwitches(dex *data.Dex, state *game.BattleState, player, opponent *game.Player) string {
+	if player.Trapped() {
+		return ""
+	}
+	options := analysis.EvaluateSwitches(dex, state, player, opponent)
+	if len(options) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("<br>Cambios:<br>")
+	for i, opt := range options {
+		if i == maxSwitchSuggestions {
+			break
+		}
+		details := []string{fmt.Sprintf("recibe ≈ %.0f%%", opt.DamageTaken*100)}
+		if opt.WorstMove != "" {
+			details[0] += " de " + escape(opt.WorstMove)
+		}
+		if opt.HazardDamage > 0 {
+			details = append(details, fmt.Sprintf("hazards %.0f%%", opt.HazardDamage*100))
+		}
+		if opt.BestMove != "" {
+			details = append(details, fmt.Sprintf("devuelve ≈ %.0f%% con %s", opt.Threat*100, escape(opt.BestMove)))
+		}
+		switch opt.Outspeeds {
+		case 1:
+			details = append(details, "más rápido")
+		case -1:
+			details = append(details, "más lento")
+		}
+		details = append(details, opt.Notes...)
+		sb.WriteString(fmt.Sprintf("%d. <b>%s</b> (%.0f%% PS) - %s<br>", i+1, escape(opt.Name), opt.HP*100, strings.Join(details, ", ")))
+	}
+	return sb.String()
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"fmt"
+	"showdown-analizer/analysis"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"strings"
+)
+
+// opponentOf devuelve el otro jugador de la batalla.
+func opponentOf(state *game.BattleState, player *game.Player) *game.Player {
+	if player.ID == "p1" {
+		return state.Players["p2"]
+	}
+	return state.Players["p1"]
+}
+
+func renderTeamReport(dex *data.Dex, state *game.BattleState, player *game.Player) string {
+	report := analysis.BuildTeamReport(dex, player, opponentOf(state, player))
+	var sb strings.Builder
+	sb.WriteString("<details class='team-report'><summary>Análisis del equipo</summary>")
+	for _, name := range report.NoAnswer {
+		sb.WriteString(fmt.Sprintf("<div class='warning'>Sin respuesta a %s</div>", escape(name)))
+	}
+	if len(report.Exposed) > 0 {
+		sb.WriteString("<div><b>Débiles sin nadie que resista:</b> " + escapeJoin(report.Exposed, ", ") + "</div>")
+	}
+	if len(report.Uncovered) > 0 {
+		sb.WriteString("<div><b>Ningún movimiento pega neutro a:</b> " + escapeJoin(report.Uncovered, ", ") + "</div>")
+	}
+	sb.WriteString(renderMatrix(report.Defensive))
+	sb.WriteString(renderCoverage(report.Offensive))
+	sb.WriteString("</details>")
+	return sb.String()
+}
+
+func renderCoverage(rows []analysis.CoverageRow) string {
+	var sb strings.Builder
+	sb.WriteString("<table class='type-matrix'><tr><th>Contra</th><th>Mejor</th><th>Movimientos</th></tr>")
+	for _, row := range rows {
+		if len(row.Moves) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("<tr><td>%s</td><td>×%g</td><td>%s</td></tr>", escape(row.Type), row.Best, escapeJoin(row.Moves, ", ")))
+	}
+	sb.WriteString("</table>")
+	return sb.String()
+}
@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"fmt"
+	"showdown-analizer/game"
+	"strings"
+)
+
+// renderWinProbability muestra la probabilidad de victoria del último turno
+// y un gráfico con su evolución. Con perspectiva se mide desde el jugador;
+// como espectador, desde p1.
+func renderWinProbability(state *game.BattleState) string {
+	if len(state.WinHistory) == 0 {
+		return ""
+	}
+	first, second := state.Players["p1"], state.Players["p2"]
+	if first == nil || second == nil {
+		return ""
+	}
+	history := state.WinHistory
+	if state.Perspective == "p2" {
+		first, second = second, first
+		history = make([]float64, len(state.WinHistory))
+		for i, p := range state.WinHistory {
+			history[i] = 1 - p
+		}
+	}
+	p := history[len(history)-1]
+
+	var sb strings.Builder
+	sb.WriteString("<div class='win-probability'>")
+	sb.WriteString(fmt.Sprintf("<b>Probabilidad de victoria:</b> %s %.0f%% – %s %.0f%%", escape(first.Name), p*100, escape(second.Name), (1-p)*100))
+	sb.WriteString(fmt.Sprintf("<div class='win-bar'><div style='width:%.0f%%'></div></div>", p*100))
+	if len(history) > 1 {
+		const width, height = 300.0, 60.0
+		points := make([]string, len(history))
+		for i, v := range history {
+			x := width * float64(i) / float64(len(history)-1)
+			points[i] = fmt.Sprintf("%.1f,%.1f", x, height*(1-v))
+		}
+		sb.WriteString(fmt.Sprintf("<svg class='win-chart' viewBox='0 0 %.0f %.0f' width='%.0f' height='%.0f'>", width, height, width, height))
+		sb.WriteString(fmt.Sprintf("<line x1='0' y1='%.0f' x2='%.0f' y2='%.0f' class='mid'/>", height/2, width, height/2))
+		sb.WriteString("<polyline points='" + strings.Join(points, " ") + "'/></svg>")
+	}
+	sb.WriteString("</div>")
+	return sb.String()
+}
@@ -0,0 +1,67 @@
+package predict
+
+import (
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"sort"
+)
+
+type Chance struct {
+	Name string
+	Prob float64
+}
+
+type Prediction struct {
+	Source    string
+	Level     int
+	Roles     []Chance
+	Moves     []Chance
+	Abilities []Chance
+	Items     []Chance
+	TeraTypes []Chance
+	Spreads   []Chance
+}
+
+// For elige la fuente de predicción adecuada para el formato de la batalla.
+// Devuelve nil si no hay datos para predecir nada sobre poke.
+func For(dex *data.Dex, format string, poke *game.Pokemon) *Prediction {
+	if poke == nil {
+		return nil
+	}
+	species := poke.Species
+	if species == "" {
+		species = poke.Name
+	}
+	if entry, ok := dex.GetRandomBattleSets(format, species); ok {
+		return RandomBattle(entry, poke)
+	}
+	if entry, stats, ok := dex.GetUsageEntry(format, 0, species); ok {
+		return Usage(dex, entry, stats, poke)
+	}
+	return nil
+}
+
+func sortedChances(weights map[string]float64, total float64) []Chance {
+	chances := make([]Chance, 0, len(weights))
+	for name, w := range weights {
+		if total <= 0 {
+			continue
+		}
+		chances = append(chances, Chance{Name: name, Prob: w / total})
+	}
+	sort.Slice(chances, func(i, j int) bool {
+		if chances[i].Prob != chances[j].Prob {
+			return chances[i].Prob > chances[j].Prob
+		}
+		return chances[i].Name < chances[j].Name
+	})
+	return chances
+}
+
+func revealedMoveIDs(poke *game.Pokemon) map[string]bool {
+	ids := make(map[string]bool, len(poke.Moves))
+	for _, m := range poke.Moves {
+		ids[data.ToID(m.Name)] = true
+	}
+	return ids
+}
@@ -0,0 +1,113 @@
+package predict
+
+import (
+	"math"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+)
+
+const randomBattleMoveSlots = 4
+
+// RandomBattle descarta los sets incompatibles con lo ya revelado de poke y
+// estima, con los sets restantes equiprobables, qué le falta por mostrar.
+func RandomBattle(entry data.RandomBattleSpecies, poke *game.Pokemon) *Prediction {
+	revealed := revealedMoveIDs(poke)
+
+	var candidates []data.RandomBattleSet
+	for _, set := range entry.Sets {
+		if setMatches(set, poke, revealed) {
+			candidates = append(candidates, set)
+		}
+	}
+	source := "random battle"
+	if len(candidates) == 0 {
+		// Los datos pueden estar desactualizados respecto del servidor; mejor
+		// una predicción aproximada que ninguna.
+		candidates = entry.Sets
+		source = "random battle (sin set compatible)"
+	}
+
+	pred := &Prediction{Source: source, Level: entry.Level}
+	if len(candidates) == 0 {
+		return pred
+	}
+
+	weight := 1.0 / float64(len(candidates))
+	roles := map[string]float64{}
+	moves := map[string]float64{}
+	abilities := map[string]float64{}
+	items := map[string]float64{}
+	teraTypes := map[string]float64{}
+
+	for _, set := range candidates {
+		if set.Role != "" {
+			roles[set.Role] += weight
+		}
+
+		known := 0
+		for _, m := range set.Movepool {
+			if revealed[data.ToID(m)] {
+				known++
+			}
+		}
+		unknown := len(set.Movepool) - known
+		if unknown > 0 {
+			p := math.Min(1, float64(randomBattleMoveSlots-known)/float64(unknown))
+			for _, m := range set.Movepool {
+				if !revealed[data.ToID(m)] && p > 0 {
+					moves[m] += weight * p
+				}
+			}
+		}
+
+		spread(abilities, set.Abilities, poke.Ability, weight)
+		spread(items, set.Items, poke.Item, weight)
+		spread(teraTypes, set.TeraTypes, poke.TeraType, weight)
+	}
+
+	pred.Roles = sortedChances(roles, 1)
+	pred.Moves = sortedChances(moves, 1)
+	pred.Abilities = sortedChances(abilities, 1)
+	pred.Items = sortedChances(items, 1)
+	pred.TeraTypes = sortedChances(teraTypes, 1)
+	return pred
+}
+
+func setMatches(set data.RandomBattleSet, poke *game.Pokemon, revealed map[string]bool) bool {
+	pool := make(map[string]bool, len(set.Movepool))
+	for _, m := range set.Movepool {
+		pool[data.ToID(m)] = true
+	}
+	for id := range revealed {
+		if !pool[id] {
+			return false
+		}
+	}
+	return allows(set.Abilities, poke.Ability) &&
+		allows(set.Items, poke.Item) &&
+		allows(set.TeraTypes, poke.TeraType)
+}
+
+func allows(options []string, value string) bool {
+	if value == "" || len(options) == 0 {
+		return true
+	}
+	for _, o := range options {
+		if data.ToID(o) == data.ToID(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// spread reparte weight entre las opciones, o lo asigna entero al valor
+// revelado si ya se conoce.
+func spread(dst map[string]float64, options []string, revealed string, weight float64) {
+	if revealed != "" {
+		dst[revealed] += weight
+		return
+	}
+	for _, o := range options {
+		dst[o] += weight / float64(len(options))
+	}
+}
@@ -0,0 +1,96 @@
+package predict
+
+import (
+	"math"
+	"showdown-analizer/calc"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"strings"
+)
+
+const usageMoveSlots = 4
+
+// Usage predice el set de poke a partir de las estadísticas chaos de Smogon.
+// Las estadísticas sólo dan marginales, así que los movimientos se
+// condicionan asumiendo independencia: la masa de los no revelados se
+// reparte entre los slots que quedan libres.
+func Usage(dex *data.Dex, entry data.UsageEntry, stats *data.UsageStats, poke *game.Pokemon) *Prediction {
+	pred := &Prediction{Source: "usage " + stats.Format}
+	if entry.RawCount <= 0 {
+		return pred
+	}
+
+	revealed := revealedMoveIDs(poke)
+	free := usageMoveSlots - len(revealed)
+	if free > 0 {
+		unrevealed := map[string]float64{}
+		mass := 0.0
+		for id, w := range entry.Moves {
+			if id == "" || revealed[id] {
+				continue
+			}
+			p := w / entry.RawCount
+			unrevealed[dex.GetMoveName(id)] = p
+			mass += p
+		}
+		if mass > 0 {
+			scale := float64(free) / mass
+			for name, p := range unrevealed {
+				unrevealed[name] = math.Min(1, p*scale)
+			}
+		}
+		pred.Moves = sortedChances(unrevealed, 1)
+	}
+
+	pred.Abilities = usageChances(entry.Abilities, poke.Ability, dex.GetAbilityName)
+	pred.Items = usageChances(entry.Items, poke.Item, dex.GetItemName)
+	pred.TeraTypes = usageChances(entry.TeraTypes, poke.TeraType, capitalize)
+	pred.Spreads = usageChances(entry.Spreads, "", nil)
+	return pred
+}
+
+func usageChances(weights map[string]float64, revealed string, name func(string) string) []Chance {
+	if revealed != "" {
+		return []Chance{{Name: revealed, Prob: 1}}
+	}
+	named := make(map[string]float64, len(weights))
+	total := 0.0
+	for id, w := range weights {
+		if id == "" || id == "nothing" {
+			continue
+		}
+		if name != nil {
+			id = name(id)
+		}
+		named[id] += w
+		total += w
+	}
+	return sortedChances(named, total)
+}
+
+func capitalize(id string) string {
+	if id == "" {
+		return id
+	}
+	return strings.ToUpper(id[:1]) + id[1:]
+}
+
+// LikelySpread es el reparto más usado de la especie de poke según las
+// estadísticas del formato.
+func LikelySpread(dex *data.Dex, format string, poke *game.Pokemon) (calc.Spread, bool) {
+	species := poke.Species
+	if species == "" {
+		species = poke.Name
+	}
+	entry, _, ok := dex.GetUsageEntry(format, 0, species)
+	if !ok {
+		return calc.Spread{}, false
+	}
+	best, bestWeight := "", 0.0
+	for spread, w := range entry.Spreads {
+		if w > bestWeight || (w == bestWeight && spread < best) {
+			best, bestWeight = spread, w
+		}
+	}
+	return calc.ParseSpread(best)
+}
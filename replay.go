@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"showdown-analizer/data"
+	"showdown-analizer/replay"
+)
+
+// Tamaño máximo de un replay subido.
+const maxReplaySize = 5 << 20
+
+// replaySlots limita los análisis de replays simultáneos: cada uno ocupa un
+// núcleo varios segundos.
+var replaySlots = make(chan struct{}, 2)
+
+// handleReplay sirve el visor de replays con GET. Con POST analiza un
+// replay terminado subido como archivo (campo "replay") y devuelve el
+// reporte turno por turno en HTML o, con ?json=1, en JSON. Si ya hay
+// demasiados análisis en curso responde 503, y si el cliente se va el
+// análisis se corta.
+func handleReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		if err := templates.ExecuteTemplate(w, "replays.html", nil); err != nil {
+			http.Error(w, "Error al renderizar la plantilla", http.StatusInternalServerError)
+		}
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxReplaySize)
+	file, _, err := r.FormFile("replay")
+	if err != nil {
+		http.Error(w, "Falta el archivo del replay", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "No se pudo leer el replay", http.StatusBadRequest)
+		return
+	}
+	rep, err := replay.Decode(raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	perspective := r.FormValue("perspective")
+	switch perspective {
+	case "p1", "p2":
+	case "", "spectator":
+		perspective = ""
+	default:
+		http.Error(w, "Perspectiva inválida: usa p1, p2 o spectator", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case replaySlots <- struct{}{}:
+		defer func() { <-replaySlots }()
+	default:
+		w.Header().Set("Retry-After", "10")
+		http.Error(w, "Hay demasiados replays en análisis, intenta en unos segundos", http.StatusServiceUnavailable)
+		return
+	}
+	report := replay.Analyze(r.Context(), data.Current(), rep, perspective, replay.AnalyzeOptions)
+	if r.Context().Err() != nil {
+		return
+	}
+	if r.URL.Query().Get("json") != "" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Printf("Error escribiendo reporte de replay: %v", err)
+		}
+		return
+	}
+	if err := templates.ExecuteTemplate(w, "replay.html", report); err != nil {
+		log.Printf("Error renderizando replay: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package replay
+
+import (
+	"context"
+	"html/template"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/parser"
+	"showdown-analizer/search"
+	"strings"
+	"time"
+)
+
+// Turn es un turno del reporte: Summary es lo que mostraba la vista en
+// vivo al empezar el turno y Lines lo que pasó durante él. El turno 0 son
+// el team preview y los leads. Summary es HTML: RenderBattleState escapa
+// todo lo que viene del log.
+type Turn struct {
+	Number    int           `json:"turn"`
+	Summary   template.HTML `json:"summary"`
+	Lines     []string      `json:"lines"`
+	Decisions []Decision    `json:"decisions,omitempty"`
+}
+
+// Diverged indica si algún jugador se apartó de la sugerencia del motor.
+func (t Turn) Diverged() bool {
+	for _, d := range t.Decisions {
+		if d.Diverged {
+			return true
+		}
+	}
+	return false
+}
+
+type Report struct {
+	ID          string    `json:"id,omitempty"`
+	Format      string    `json:"format"`
+	Perspective string    `json:"perspective,omitempty"`
+	Players     []string  `json:"players"`
+	Winner      string    `json:"winner,omitempty"`
+	WinHistory  []float64 `json:"winHistory,omitempty"`
+	Turns       []Turn    `json:"turns"`
+	Review      Review    `json:"review"`
+}
+
+// Líneas del log que no hacen a la batalla.
+var skipped = []string{"|t:|", "|j|", "|J|", "|l|", "|L|", "|n|", "|c|", "|c:|", "|chat|", "|raw|", "|html|", "|uhtml", "|inactive", "|request|"}
+
+// AnalyzeOptions es la búsqueda con que se juzga cada decisión de un
+// replay. Se corre una por jugador y turno, así que es mucho más corta que
+// la del vivo para que un replay largo entre en un pedido HTTP.
+var AnalyzeOptions = search.Options{Budget: 20 * time.Millisecond, MaxDepth: 2, Samples: 3, Seed: 1}
+
+// Turnos que se juzgan como máximo; los siguientes quedan sin decisiones.
+// Con AnalyzeOptions acota la búsqueda a unos 4s por replay.
+const maxJudgedTurns = 100
+
+// Analyze reproduce el log de r con dex desde la perspectiva dada ("",
+// "p1" o "p2") y arma el reporte turno por turno. opts es la búsqueda que
+// juzga cada decisión; si ctx termina, los turnos que faltan quedan sin
+// juzgar.
+func Analyze(ctx context.Context, dex *data.Dex, r Replay, perspective string, opts search.Options) Report {
+	dex = dex.ForGen(data.GenFromFormat(r.Format))
+	state := game.NewBattleState()
+	state.Format = r.Format
+	state.Perspective = perspective
+
+	turns := []Turn{{}}
+	for _, line := range strings.Split(r.Log, "\n") {
+		line = strings.TrimSpace(line)
+		parser.ProcessLine(dex, state, line)
+
+		current := &turns[len(turns)-1]
+		switch {
+		case strings.HasPrefix(line, "|turn|"):
+			turns = append(turns, Turn{Number: state.Turn, Summary: template.HTML(parser.RenderBattleState(dex, state))})
+			continue
+		case line == "|teampreview" || strings.HasPrefix(line, "|teampreview|"):
+			current.Summary = template.HTML(parser.RenderBattleState(dex, state))
+		}
+		if battleLine(line) {
+			current.Lines = append(current.Lines, line)
+		}
+	}
+
+	for i := range turns {
+		turns[i].Decisions = decisions(ctx, dex, state, turns[i], opts)
+	}
+
+	report := Report{
+		ID:          r.ID,
+		Format:      state.Format,
+		Perspective: perspective,
+		Players:     r.Players,
+		WinHistory:  state.WinHistory,
+		Turns:       turns,
+	}
+	if len(report.Players) == 0 {
+		for _, p := range state.OrderedPlayers() {
+			report.Players = append(report.Players, p.Name)
+		}
+	}
+	if w := state.Players[state.Winner]; w != nil {
+		report.Winner = w.Name
+	}
+	report.Review = BuildReview(state, turns)
+	return report
+}
+
+func battleLine(line string) bool {
+	if len(line) < 2 || line[0] != '|' {
+		return false
+	}
+	for _, prefix := range skipped {
+		if strings.HasPrefix(line, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// decisions compara las elecciones del turno con el snapshot de su
+// comienzo. Con perspectiva sólo se juzga al propio jugador. Si ctx ya
+// terminó no se juzga nada.
+func decisions(ctx context.Context, dex *data.Dex, state *game.BattleState, turn Turn, opts search.Options) []Decision {
+	snap, ok := state.AtTurn(turn.Number)
+	if turn.Number == 0 || turn.Number > maxJudgedTurns || !ok || ctx.Err() != nil {
+		return nil
+	}
+	actual := ActualChoices(turn.Lines)
+	var out []Decision
+	for _, player := range snap.OrderedPlayers() {
+		if self := snap.Self(); self != nil && player != self {
+			continue
+		}
+		choice, ok := actual[player.ID]
+		if !ok {
+			continue
+		}
+		if d, ok := decide(dex, snap, player, opponentOf(snap, player), choice, opts); ok {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func opponentOf(state *game.BattleState, player *game.Player) *game.Player {
+	if player.ID == "p1" {
+		return state.Players["p2"]
+	}
+	return state.Players["p1"]
+}
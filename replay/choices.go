@@ -0,0 +1,97 @@
+package replay
+
+import (
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/search"
+	"strings"
+)
+
+// Decision compara lo que eligió un jugador en un turno con lo que el motor
+// ponía primero al empezar ese turno. Loss es cuánto valor perdió según la
+// búsqueda; 0 si coincidió o si la elección real no estaba evaluada.
+type Decision struct {
+	Player    string        `json:"player"`
+	Name      string        `json:"name"`
+	Actual    search.Choice `json:"actual"`
+	Suggested search.Choice `json:"suggested"`
+	Diverged  bool          `json:"diverged"`
+	Loss      float64       `json:"loss"`
+}
+
+// LossPoints es Loss en la escala en que se muestran los valores de la
+// búsqueda.
+func (d Decision) LossPoints() float64 {
+	return d.Loss * 100
+}
+
+// ActualChoices deduce de las líneas de un turno qué eligió cada jugador:
+// el primer |move| propio o un |switch| antes de moverse. Los cambios
+// forzados después de |upkeep| no cuentan, y |cant| deja la elección sin
+// conocer.
+func ActualChoices(lines []string) map[string]search.Choice {
+	choices := map[string]search.Choice{}
+	decided := map[string]bool{}
+	for _, line := range lines {
+		if line == "|upkeep" {
+			break
+		}
+		parts := strings.Split(line, "|")
+		if len(parts) < 3 || len(parts[2]) < 2 {
+			continue
+		}
+		player := parts[2][:2]
+		if decided[player] {
+			continue
+		}
+		if parts[1] == "faint" {
+			// Lo que entre después es un reemplazo, no una elección.
+			decided[player] = true
+			continue
+		}
+		_, name, _ := strings.Cut(parts[2], ": ")
+		switch parts[1] {
+		case "move":
+			if len(parts) < 4 || strings.Contains(line, "[from]") {
+				continue
+			}
+			choices[player] = search.Choice{Name: parts[3]}
+		case "switch":
+			if len(parts) < 4 {
+				continue
+			}
+			choices[player] = search.Choice{Switch: true, Name: name}
+		case "cant":
+		default:
+			continue
+		}
+		decided[player] = true
+	}
+	return choices
+}
+
+// decide arma la Decision de player en el turno que empieza en snap.
+func decide(dex *data.Dex, snap *game.BattleState, player, opponent *game.Player, actual search.Choice, opts search.Options) (Decision, bool) {
+	report := search.Search(dex, snap, player, opponent, opts)
+	if len(report.Results) == 0 {
+		return Decision{}, false
+	}
+	best := report.Results[0]
+	d := Decision{Player: player.ID, Name: player.Name, Actual: actual, Suggested: best.Choice}
+	// Contra un ataque genérico sólo se puede juzgar si cambió o no.
+	unknown := !best.Choice.Switch && best.Choice.Name == search.UnknownMove && !actual.Switch
+	if unknown || sameChoice(actual, best.Choice) {
+		return d, true
+	}
+	d.Diverged = true
+	for _, r := range report.Results {
+		if sameChoice(actual, r.Choice) {
+			d.Loss = best.Value - r.Value
+		}
+	}
+	return d, true
+}
+
+func sameChoice(a, b search.Choice) bool {
+	return a.Switch == b.Switch && data.ToID(a.Name) == data.ToID(b.Name)
+}
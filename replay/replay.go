@@ -0,0 +1,69 @@
+// Package replay lee batallas terminadas, como las que sirve
+// replay.pokemonshowdown.com, y las analiza offline turno por turno con el
+// mismo parser y los mismos resúmenes que la vista en vivo.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"showdown-analizer/data"
+	"strings"
+)
+
+// ErrUnsupported indica un archivo que no es un log ni un replay.
+var ErrUnsupported = errors.New("no es un log (.log, .txt) ni un replay (.json)")
+
+// Replay es una batalla terminada. Los campos son los del .json del sitio
+// de replays; de un .log sólo se completan Log y Format.
+type Replay struct {
+	ID      string   `json:"id"`
+	Format  string   `json:"formatid"`
+	Players []string `json:"players"`
+	Log     string   `json:"log"`
+}
+
+// Decode acepta el texto del .log o el .json de un replay.
+func Decode(raw []byte) (Replay, error) {
+	var r Replay
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &r); err != nil {
+			return Replay{}, fmt.Errorf("replay inválido: %w", err)
+		}
+	} else {
+		r.Log = string(raw)
+	}
+	if !strings.Contains(r.Log, "|") {
+		return Replay{}, errors.New("el replay no tiene log de protocolo")
+	}
+	if r.Format == "" {
+		r.Format = formatFromLog(r.Log)
+	}
+	return r, nil
+}
+
+// ReadFile lee un replay según su extensión.
+func ReadFile(path string) (Replay, error) {
+	switch filepath.Ext(path) {
+	case ".log", ".txt", ".json":
+	default:
+		return Replay{}, ErrUnsupported
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Replay{}, err
+	}
+	return Decode(raw)
+}
+
+func formatFromLog(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if tier, ok := strings.CutPrefix(strings.TrimSpace(line), "|tier|"); ok {
+			return data.ToID(tier)
+		}
+	}
+	return ""
+}
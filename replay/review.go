@@ -0,0 +1,220 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"math"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/search"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Pérdida de valor de búsqueda o caída de probabilidad a partir de la
+	// cual una elección distinta a la sugerida cuenta como error probable.
+	minMistakeLoss  = 0.1
+	minMistakeSwing = 0.1
+	maxMistakes     = 5
+	maxTurningPoint = 3
+)
+
+// Mistake es una elección que se apartó de la sugerencia y coincidió con
+// una caída en la probabilidad de victoria. Swing es el cambio en la
+// probabilidad del jugador durante ese turno.
+type Mistake struct {
+	Turn int `json:"turn"`
+	Decision
+	Swing float64 `json:"swing"`
+}
+
+// TurningPoint es uno de los turnos en los que más se movió la
+// probabilidad de victoria; Swing es a favor de Player.
+type TurningPoint struct {
+	Turn   int      `json:"turn"`
+	Player string   `json:"player"`
+	Swing  float64  `json:"swing"`
+	Lines  []string `json:"lines"`
+}
+
+// Review es el análisis de una partida terminada.
+type Review struct {
+	Winner        string         `json:"winner,omitempty"`
+	TurningPoints []TurningPoint `json:"turningPoints"`
+	Mistakes      []Mistake      `json:"mistakes"`
+}
+
+// ReviewLog arma la revisión de una partida en vivo a partir de su log
+// completo. state tiene que haber procesado ese mismo log, para usar sus
+// snapshots y su historial de probabilidad. opts es la búsqueda que juzga
+// cada decisión; si ctx termina, los turnos que faltan quedan sin juzgar.
+func ReviewLog(ctx context.Context, dex *data.Dex, state *game.BattleState, lines []string, opts search.Options) Review {
+	turns := splitTurns(lines)
+	for i := range turns {
+		turns[i].Decisions = decisions(ctx, dex, state, turns[i], opts)
+	}
+	return BuildReview(state, turns)
+}
+
+// BuildReview busca los turnos decisivos y los errores probables con las
+// decisiones ya calculadas de cada turno.
+func BuildReview(state *game.BattleState, turns []Turn) Review {
+	var rv Review
+	if w := state.Players[state.Winner]; w != nil {
+		rv.Winner = w.Name
+	}
+
+	for _, turn := range turns {
+		swing, ok := p1Swing(state, turn.Number)
+		if !ok {
+			continue
+		}
+		if swing != 0 {
+			tp := TurningPoint{Turn: turn.Number, Player: playerName(state, "p1"), Swing: swing, Lines: turn.Lines}
+			if swing < 0 {
+				tp.Player, tp.Swing = playerName(state, "p2"), -swing
+			}
+			rv.TurningPoints = append(rv.TurningPoints, tp)
+		}
+		for _, d := range turn.Decisions {
+			own := swing
+			if d.Player == "p2" {
+				own = -swing
+			}
+			if d.Diverged && (d.Loss >= minMistakeLoss || own <= -minMistakeSwing) {
+				rv.Mistakes = append(rv.Mistakes, Mistake{Turn: turn.Number, Decision: d, Swing: own})
+			}
+		}
+	}
+
+	sort.SliceStable(rv.TurningPoints, func(i, j int) bool { return rv.TurningPoints[i].Swing > rv.TurningPoints[j].Swing })
+	if len(rv.TurningPoints) > maxTurningPoint {
+		rv.TurningPoints = rv.TurningPoints[:maxTurningPoint]
+	}
+	// Primero los que más probabilidad y valor de búsqueda perdieron.
+	sort.SliceStable(rv.Mistakes, func(i, j int) bool {
+		return rv.Mistakes[i].Swing-rv.Mistakes[i].Loss < rv.Mistakes[j].Swing-rv.Mistakes[j].Loss
+	})
+	if len(rv.Mistakes) > maxMistakes {
+		rv.Mistakes = rv.Mistakes[:maxMistakes]
+	}
+	return rv
+}
+
+// p1Swing es cuánto cambió la probabilidad de victoria de p1 durante el
+// turno n. Para el último turno se compara con el resultado final.
+func p1Swing(state *game.BattleState, n int) (float64, bool) {
+	history := state.WinHistory
+	if n < 1 || n > len(history) {
+		return 0, false
+	}
+	var after float64
+	switch {
+	case n < len(history):
+		after = history[n]
+	case state.Winner == "p1":
+		after = 1
+	case state.Winner == "p2":
+		after = 0
+	default:
+		return 0, false
+	}
+	return after - history[n-1], true
+}
+
+func playerName(state *game.BattleState, id string) string {
+	if p := state.Players[id]; p != nil {
+		return p.Name
+	}
+	return id
+}
+
+// splitTurns separa un log en turnos como Analyze, sin reproducirlo.
+func splitTurns(lines []string) []Turn {
+	turns := []Turn{{}}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if n, ok := strings.CutPrefix(line, "|turn|"); ok {
+			number, _ := strconv.Atoi(n)
+			turns = append(turns, Turn{Number: number})
+			continue
+		}
+		if battleLine(line) {
+			turns[len(turns)-1].Lines = append(turns[len(turns)-1].Lines, line)
+		}
+	}
+	return turns
+}
+
+// RenderReview arma el reporte post-partida en una sola línea de HTML,
+// para poder mandarlo por SSE.
+func RenderReview(rv Review) string {
+	var sb strings.Builder
+	sb.WriteString("<div class='post-game'><h3>Revisión de la partida</h3>")
+	if rv.Winner != "" {
+		sb.WriteString(fmt.Sprintf("<p>Ganó <b>%s</b>.</p>", template.HTMLEscapeString(rv.Winner)))
+	}
+
+	sb.WriteString("<b>Turnos decisivos:</b>")
+	if len(rv.TurningPoints) == 0 {
+		sb.WriteString(" sin datos de probabilidad.<br>")
+	} else {
+		sb.WriteString("<ul>")
+		for _, tp := range rv.TurningPoints {
+			sb.WriteString(fmt.Sprintf("<li>Turno %d: %+.0f%% para %s", tp.Turn, tp.Swing*100, template.HTMLEscapeString(tp.Player)))
+			if summary := keyEvents(tp.Lines); summary != "" {
+				sb.WriteString(" <span style='color:#9b9b9b;'>(" + template.HTMLEscapeString(summary) + ")</span>")
+			}
+			sb.WriteString("</li>")
+		}
+		sb.WriteString("</ul>")
+	}
+
+	sb.WriteString("<b>Errores probables:</b>")
+	if len(rv.Mistakes) == 0 {
+		sb.WriteString(" ninguno claro.")
+	} else {
+		sb.WriteString("<ul>")
+		for _, m := range rv.Mistakes {
+			sb.WriteString(fmt.Sprintf("<li>Turno %d, <b>%s</b>: eligió %s, el motor sugería <b>%s</b>",
+				m.Turn, template.HTMLEscapeString(m.Name), template.HTMLEscapeString(m.Actual.String()), template.HTMLEscapeString(m.Suggested.String())))
+			if m.Loss > 0 {
+				sb.WriteString(fmt.Sprintf(" (valor -%.0f)", m.LossPoints()))
+			}
+			if math.Abs(m.Swing) >= 0.005 {
+				sb.WriteString(fmt.Sprintf(", probabilidad %+.0f%%", m.Swing*100))
+			}
+			sb.WriteString("</li>")
+		}
+		sb.WriteString("</ul>")
+	}
+	sb.WriteString("</div>")
+	return sb.String()
+}
+
+// HTML es RenderReview para usar desde plantillas.
+func (rv Review) HTML() template.HTML {
+	return template.HTML(RenderReview(rv))
+}
+
+// keyEvents resume un turno con sus movimientos y debilitados.
+func keyEvents(lines []string) string {
+	var events []string
+	for _, line := range lines {
+		parts := strings.Split(line, "|")
+		if len(parts) < 3 {
+			continue
+		}
+		_, who, _ := strings.Cut(parts[2], ": ")
+		switch {
+		case parts[1] == "move" && len(parts) >= 4:
+			events = append(events, who+" usó "+parts[3])
+		case parts[1] == "faint":
+			events = append(events, who+" cayó")
+		}
+	}
+	return strings.Join(events, ", ")
+}
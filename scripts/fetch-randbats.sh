@@ -0,0 +1,52 @@
+#!/bin/sh
+# Descarga los sets de random battle de Showdown en data/random-battles,
+# comprimidos como el pokedex, para que queden embebidos en el binario.
+# Se bajan del commit que nombra data/VERSION (showdown-AAAA-MM-DD es el
+# último commit de master de ese día) para que coincidan con el resto de
+# los datos; SHOWDOWN_REF fija otro commit. Las gens que ya tienen sets no
+# se vuelven a bajar. Sale con error si alguna gen queda sin sets.
+set -eu
+
+ROOT="$(dirname "$0")/.."
+DEST="$ROOT/data/random-battles"
+REPO=smogon/pokemon-showdown
+
+ref="${SHOWDOWN_REF:-}"
+if [ -z "$ref" ]; then
+	day="$(sed -n 's/^showdown-\([0-9]\{4\}-[0-9]\{2\}-[0-9]\{2\}\)$/\1/p' "$ROOT/data/VERSION")"
+	if [ -z "$day" ]; then
+		echo "data/VERSION no tiene la forma showdown-AAAA-MM-DD" >&2
+		exit 1
+	fi
+	ref="$(wget -q -O - "https://api.github.com/repos/$REPO/commits?sha=master&until=${day}T23:59:59Z&per_page=1" |
+		grep -o '"sha": *"[0-9a-f]\{40\}"' | head -n 1 | grep -o '[0-9a-f]\{40\}')" || true
+	if [ -z "$ref" ]; then
+		echo "no se pudo resolver el commit de Showdown del $day" >&2
+		exit 1
+	fi
+fi
+BASE="${SHOWDOWN_RAW_URL:-https://raw.githubusercontent.com/$REPO}/$ref"
+echo "sets de random battle de $REPO@$ref"
+
+for gen in 1 2 3 4 5 6 7 8 9; do
+	dir="$DEST/gen$gen"
+	mkdir -p "$dir"
+	if [ -s "$dir/sets.json.gz" ] || [ -s "$dir/data.json.gz" ]; then
+		continue
+	fi
+	found=""
+	# Cada gen usa sets.json o, en las viejas, data.json.
+	for name in sets.json data.json; do
+		if wget -q -O "$dir/$name" "$BASE/data/random-battles/gen$gen/$name"; then
+			gzip -9f "$dir/$name"
+			echo "gen$gen/$name"
+			found=1
+			break
+		fi
+		rm -f "$dir/$name"
+	done
+	if [ -z "$found" ]; then
+		echo "no se pudieron bajar los sets de gen$gen desde $BASE" >&2
+		exit 1
+	fi
+done
@@ -0,0 +1,274 @@
+package search
+
+import (
+	"math"
+	"math/rand"
+	"showdown-analizer/analysis"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/infer"
+	"showdown-analizer/predict"
+	"showdown-analizer/speed"
+)
+
+// action es un movimiento de un unit. name vacío es el STAB nominal que se
+// usa cuando no hay movimientos conocidos.
+type action struct {
+	name     string
+	priority int
+}
+
+// unit es un Pokémon dentro del modelo de búsqueda, con la vida como
+// fracción de la máxima.
+type unit struct {
+	poke    *game.Pokemon
+	hp      float64
+	hazard  float64
+	speed   float64
+	actions []action
+}
+
+// side es un equipo. units son los Pokémon vivos que se modelan; reserve
+// es la vida de los que no (sin revelar, a vida llena, o sin tipos
+// conocidos) y size el tamaño del equipo, con los debilitados.
+type side struct {
+	units   []unit
+	active  int
+	reserve float64
+	size    int
+}
+
+// Tamaño de equipo que se asume si no se anunció con |teamsize|.
+const defaultTeamSize = 6
+
+// node es un estado del modelo: el lado 0 es el jugador que busca y el 1 el
+// rival.
+type node struct {
+	sides [2]side
+}
+
+// model guarda lo que no cambia entre nodos: daño precalculado por
+// atacante, defensor y movimiento, y Trick Room.
+type model struct {
+	damage    [2][][][]float64
+	trickRoom bool
+	trapped   bool
+}
+
+func (n node) clone() node {
+	for i := range n.sides {
+		n.sides[i].units = append([]unit(nil), n.sides[i].units...)
+	}
+	return n
+}
+
+func (s side) alive() int {
+	count := 0
+	for _, u := range s.units {
+		if u.hp > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// newSide arma el lado de player con su equipo conocido. Para el rival los
+// movimientos no revelados se sortean con la predicción.
+func newSide(dex *data.Dex, state *game.BattleState, player *game.Player, rng *rand.Rand, sample bool) side {
+	s := side{active: -1, size: player.TeamSize}
+	if s.size == 0 {
+		s.size = defaultTeamSize
+	}
+	s.size = max(s.size, len(player.Team))
+	s.reserve = float64(s.size - len(player.Team))
+	for _, poke := range analysis.TeamMembers(player, true) {
+		u := unit{poke: poke, hp: 1}
+		if poke.MaxHP > 0 {
+			u.hp = float64(poke.HP) / float64(poke.MaxHP)
+		}
+		if len(poke.Type) == 0 {
+			s.reserve += u.hp
+			continue
+		}
+		if poke == player.Active {
+			s.active = len(s.units)
+			if r := speed.Effective(dex, state, player); r.Known() {
+				u.speed = float64(r.Min+r.Max) / 2
+			}
+		} else {
+			u.hazard, _ = analysis.HazardDamage(dex, player, poke)
+			if r := analysis.EntrySpeed(dex, state, player, poke); r.Known() {
+				u.speed = float64(r.Min+r.Max) / 2
+			}
+		}
+		for _, m := range poke.Moves {
+			if poke == player.Active && (player.DisabledMove(m.Name) || m.PP() == 0) {
+				continue
+			}
+			u.actions = append(u.actions, action{name: m.Name, priority: dex.GetMovePriority(m.Name)})
+		}
+		if sample && len(poke.Moves) < 4 {
+			if pred := predict.For(dex, state.Format, poke); pred != nil {
+				for _, c := range pred.Moves {
+					if len(u.actions) >= 4 {
+						break
+					}
+					if rng.Float64() < c.Prob {
+						u.actions = append(u.actions, action{name: c.Name, priority: dex.GetMovePriority(c.Name)})
+					}
+				}
+			}
+		}
+		if len(u.actions) == 0 {
+			u.actions = []action{{}}
+		}
+		s.units = append(s.units, u)
+	}
+	return s
+}
+
+// newModel precalcula el daño medio de cada movimiento de cada unit contra
+// cada unit rival.
+func newModel(dex *data.Dex, state *game.BattleState, n node) *model {
+	m := &model{trickRoom: speed.TrickRoom(state)}
+	for atkSide := 0; atkSide < 2; atkSide++ {
+		attackers, defenders := n.sides[atkSide].units, n.sides[1-atkSide].units
+		m.damage[atkSide] = make([][][]float64, len(attackers))
+		for i, a := range attackers {
+			m.damage[atkSide][i] = make([][]float64, len(defenders))
+			for j, d := range defenders {
+				row := make([]float64, len(a.actions))
+				for k, act := range a.actions {
+					row[k] = expectedDamage(dex, state, a.poke, d.poke, act.name)
+				}
+				m.damage[atkSide][i][j] = row
+			}
+		}
+	}
+	return m
+}
+
+func expectedDamage(dex *data.Dex, state *game.BattleState, attacker, defender *game.Pokemon, move string) float64 {
+	if move != "" {
+		if lo, hi, ok := infer.EstimateDamage(dex, state, attacker, defender, move); ok {
+			return (lo + hi) / 2
+		}
+		return 0
+	}
+	best := 0.0
+	for _, t := range attacker.STABTypes() {
+		best = math.Max(best, dex.Effectiveness(t, defender.Type, data.EffectivenessOptions{})*analysis.UnknownSTABDamage)
+	}
+	return best
+}
+
+// choices devuelve las acciones posibles del lado i: movimientos del activo
+// (índices >= 0) y cambios (-1 - índice del unit que entra).
+func (m *model) choices(n node, i int) []int {
+	s := n.sides[i]
+	var out []int
+	if s.active >= 0 && s.units[s.active].hp > 0 {
+		for k := range s.units[s.active].actions {
+			out = append(out, k)
+		}
+	}
+	if i == 0 && m.trapped && len(out) > 0 {
+		return out
+	}
+	for j, u := range s.units {
+		if j != s.active && u.hp > 0 {
+			out = append(out, -1-j)
+		}
+	}
+	return out
+}
+
+func (m *model) switchIn(s *side, j int) {
+	s.active = j
+	s.units[j].hp = math.Max(0, s.units[j].hp-s.units[j].hazard)
+}
+
+// replace elige el reemplazo de un activo debilitado: el de más vida.
+func (m *model) replace(s *side) {
+	best := -1
+	for j, u := range s.units {
+		if u.hp > 0 && (best < 0 || u.hp > s.units[best].hp) {
+			best = j
+		}
+	}
+	if best >= 0 {
+		m.switchIn(s, best)
+	}
+}
+
+// step aplica un turno: primero los cambios, después los movimientos en
+// orden de prioridad y velocidad, y al final los reemplazos forzados.
+func (m *model) step(n node, choice [2]int) node {
+	n = n.clone()
+	for i := 0; i < 2; i++ {
+		if choice[i] < 0 {
+			m.switchIn(&n.sides[i], -1-choice[i])
+		}
+	}
+
+	first := m.firstMover(n, choice)
+	for _, i := range [2]int{first, 1 - first} {
+		if choice[i] < 0 {
+			continue
+		}
+		atk, def := &n.sides[i], &n.sides[1-i]
+		if atk.active < 0 || def.active < 0 || atk.units[atk.active].hp <= 0 {
+			continue
+		}
+		target := &def.units[def.active]
+		target.hp = math.Max(0, target.hp-m.damage[i][atk.active][def.active][choice[i]])
+	}
+
+	for i := 0; i < 2; i++ {
+		if s := &n.sides[i]; s.active >= 0 && s.units[s.active].hp <= 0 {
+			m.replace(s)
+		}
+	}
+	return n
+}
+
+func (m *model) firstMover(n node, choice [2]int) int {
+	if choice[0] < 0 || choice[1] < 0 {
+		return 0
+	}
+	a := n.sides[0].units[n.sides[0].active]
+	b := n.sides[1].units[n.sides[1].active]
+	if pa, pb := a.actions[choice[0]].priority, b.actions[choice[1]].priority; pa != pb {
+		if pa > pb {
+			return 0
+		}
+		return 1
+	}
+	faster := 0
+	if b.speed > a.speed {
+		faster = 1
+	}
+	if m.trickRoom {
+		return 1 - faster
+	}
+	return faster
+}
+
+// evaluate puntúa el nodo desde el lado 0: vida media restante de todo el
+// equipo propio menos la del rival. Los debilitados cuentan como 0 y los
+// que no se modelan con su reserva.
+func evaluate(n node) float64 {
+	score := 0.0
+	for i, sign := range [2]float64{1, -1} {
+		s := n.sides[i]
+		if s.size == 0 {
+			continue
+		}
+		total := s.reserve
+		for _, u := range s.units {
+			total += u.hp
+		}
+		score += sign * total / float64(s.size)
+	}
+	return score
+}
@@ -0,0 +1,203 @@
+package search
+
+import (
+	"math"
+	"math/rand"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"sort"
+	"time"
+)
+
+// Options limita la búsqueda. Budget es el tiempo total; la profundidad se
+// va aumentando mientras quede tiempo, hasta MaxDepth turnos.
+type Options struct {
+	Budget   time.Duration
+	MaxDepth int
+	Samples  int
+	Seed     int64
+}
+
+// DefaultOptions entra en una actualización del loop de SSE sin demorarla.
+var DefaultOptions = Options{Budget: 150 * time.Millisecond, MaxDepth: 4, Samples: 6, Seed: 1}
+
+type Choice struct {
+	Switch bool   `json:"switch"`
+	Name   string `json:"name"`
+}
+
+func (c Choice) String() string {
+	if c.Switch {
+		return "Cambio a " + c.Name
+	}
+	return c.Name
+}
+
+// Result es el valor esperado de una elección: diferencia de vida media
+// restante entre ambos equipos al final de la búsqueda, entre -1 y 1.
+type Result struct {
+	Choice Choice  `json:"choice"`
+	Value  float64 `json:"value"`
+}
+
+type Report struct {
+	Results []Result `json:"results"`
+	Depth   int      `json:"depth"`
+	Samples int      `json:"samples"`
+}
+
+// Qué tan fuerte prefiere el rival los movimientos que más daño hacen.
+const opponentGreed = 6.0
+
+// Search evalúa las elecciones de player contra opponent con expectimax
+// sobre el modelo simplificado. La incertidumbre sobre el set rival se
+// cubre sorteando sus movimientos con la predicción en cada muestra.
+func Search(dex *data.Dex, state *game.BattleState, player, opponent *game.Player, opts Options) Report {
+	if player == nil || opponent == nil || player.Active == nil || opponent.Active == nil {
+		return Report{}
+	}
+	deadline := time.Now().Add(opts.Budget)
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	self := newSide(dex, state, player, rng, false)
+	if self.active < 0 {
+		return Report{}
+	}
+	type sample struct {
+		root  node
+		model *model
+	}
+	var samples []sample
+	for i := 0; i < max(opts.Samples, 1); i++ {
+		root := node{sides: [2]side{self, newSide(dex, state, opponent, rng, true)}}
+		if root.sides[1].active < 0 {
+			return Report{}
+		}
+		m := newModel(dex, state, root)
+		m.trapped = player.Trapped()
+		samples = append(samples, sample{root, m})
+		if i > 0 && time.Now().After(deadline) {
+			break
+		}
+	}
+
+	rootChoices := samples[0].model.choices(samples[0].root, 0)
+	report := Report{Samples: len(samples)}
+	var best []float64
+	for depth := 1; depth <= opts.MaxDepth; depth++ {
+		totals := make([]float64, len(rootChoices))
+		aborted := false
+		for _, s := range samples {
+			values, ok := s.model.rootValues(s.root, rootChoices, depth, deadline)
+			if !ok {
+				aborted = true
+				break
+			}
+			for i, v := range values {
+				totals[i] += v
+			}
+		}
+		if aborted {
+			break
+		}
+		best, report.Depth = totals, depth
+	}
+	if best == nil {
+		return report
+	}
+
+	for i, c := range rootChoices {
+		report.Results = append(report.Results, Result{
+			Choice: describe(self, c),
+			Value:  best[i] / float64(len(samples)),
+		})
+	}
+	sort.SliceStable(report.Results, func(i, j int) bool { return report.Results[i].Value > report.Results[j].Value })
+	return report
+}
+
+// UnknownMove nombra un ataque STAB genérico de un Pokémon del que no se
+// conocen movimientos.
+const UnknownMove = "STAB"
+
+func describe(s side, c int) Choice {
+	if c < 0 {
+		return Choice{Switch: true, Name: s.units[-1-c].poke.Name}
+	}
+	name := s.units[s.active].actions[c].name
+	if name == "" {
+		name = UnknownMove
+	}
+	return Choice{Name: name}
+}
+
+func (m *model) rootValues(n node, mine []int, depth int, deadline time.Time) ([]float64, bool) {
+	values := make([]float64, len(mine))
+	for i, a := range mine {
+		v, ok := m.expect(n, a, depth, deadline)
+		if !ok {
+			return nil, false
+		}
+		values[i] = v
+	}
+	return values, true
+}
+
+// expect es el valor de elegir a en n, promediando las respuestas del rival
+// según su política.
+func (m *model) expect(n node, a int, depth int, deadline time.Time) (float64, bool) {
+	foe := m.choices(n, 1)
+	weights := m.opponentPolicy(n, foe)
+	total := 0.0
+	for i, b := range foe {
+		v, ok := m.value(m.step(n, [2]int{a, b}), depth-1, deadline)
+		if !ok {
+			return 0, false
+		}
+		total += weights[i] * v
+	}
+	return total, true
+}
+
+func (m *model) value(n node, depth int, deadline time.Time) (float64, bool) {
+	if time.Now().After(deadline) {
+		return 0, false
+	}
+	if n.sides[0].alive() == 0 || n.sides[1].alive() == 0 || depth == 0 {
+		return evaluate(n), true
+	}
+	mine, foe := m.choices(n, 0), m.choices(n, 1)
+	if len(mine) == 0 || len(foe) == 0 {
+		return evaluate(n), true
+	}
+	best := math.Inf(-1)
+	for _, a := range mine {
+		v, ok := m.expect(n, a, depth, deadline)
+		if !ok {
+			return 0, false
+		}
+		best = math.Max(best, v)
+	}
+	return best, true
+}
+
+// opponentPolicy reparte la probabilidad de cada elección rival con un
+// softmax sobre el daño inmediato que hace; los cambios cuentan como un
+// golpe flojo.
+func (m *model) opponentPolicy(n node, foe []int) []float64 {
+	weights := make([]float64, len(foe))
+	mySide, foeSide := n.sides[0], n.sides[1]
+	total := 0.0
+	for i, b := range foe {
+		damage := 0.1
+		if b >= 0 && mySide.active >= 0 {
+			damage = m.damage[1][foeSide.active][mySide.active][b]
+		}
+		weights[i] = math.Exp(opponentGreed * math.Min(damage, 1))
+		total += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+	return weights
+}
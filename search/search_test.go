@@ -0,0 +1,77 @@
+package search
+
+import (
+	"math/rand"
+	"reflect"
+	"showdown-analizer/data/datatest"
+	"showdown-analizer/game"
+	"testing"
+	"time"
+)
+
+// duel arma una batalla gen9ou entre Garchomp, que conoce Earthquake y
+// Dragon Claw, y un Heatran sin movimientos conocidos.
+func duel() (*game.BattleState, *game.Player, *game.Player) {
+	chomp := &game.Pokemon{
+		Name: "Garchomp", Species: "Garchomp", Type: []string{"Dragon", "Ground"},
+		HP: 100, MaxHP: 100,
+		Moves: []game.Move{{Name: "Dragon Claw"}, {Name: "Earthquake"}},
+	}
+	tran := &game.Pokemon{
+		Name: "Heatran", Species: "Heatran", Type: []string{"Fire", "Steel"},
+		HP: 100, MaxHP: 100,
+	}
+	state := game.NewBattleState()
+	state.Format = "gen9ou"
+	p1 := &game.Player{ID: "p1", Active: chomp, Team: map[string]*game.Pokemon{chomp.Name: chomp}}
+	p2 := &game.Player{ID: "p2", Active: tran, Team: map[string]*game.Pokemon{tran.Name: tran}}
+	state.Players["p1"], state.Players["p2"] = p1, p2
+	return state, p1, p2
+}
+
+func TestSearchChoosesSuperEffectiveMove(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	state, p1, p2 := duel()
+	opts := Options{Budget: time.Minute, MaxDepth: 2, Samples: 2, Seed: 1}
+
+	report := Search(dex, state, p1, p2, opts)
+	if len(report.Results) == 0 {
+		t.Fatal("la búsqueda no devolvió resultados")
+	}
+	if got := report.Results[0].Choice.Name; got != "Earthquake" {
+		t.Errorf("mejor elección %q, se esperaba Earthquake", got)
+	}
+	if report.Depth != opts.MaxDepth {
+		t.Errorf("profundidad %d, se esperaba %d", report.Depth, opts.MaxDepth)
+	}
+	if again := Search(dex, state, p1, p2, opts); !reflect.DeepEqual(again, report) {
+		t.Errorf("con la misma semilla se esperaba el mismo reporte: %+v != %+v", again, report)
+	}
+}
+
+func TestSearchBudget(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	state, p1, p2 := duel()
+
+	report := Search(dex, state, p1, p2, Options{Budget: 0, MaxDepth: 4, Samples: 1, Seed: 1})
+	if report.Depth != 0 || len(report.Results) != 0 {
+		t.Errorf("sin tiempo se esperaba un reporte vacío, se obtuvo profundidad %d y %d resultados",
+			report.Depth, len(report.Results))
+	}
+}
+
+func TestEvaluateCountsWholeTeam(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	state, p1, p2 := duel()
+	rng := rand.New(rand.NewSource(1))
+
+	// p1 tiene cinco debilitados y Garchomp a vida llena; p2 sólo reveló a
+	// Heatran, los otros cinco cuentan a vida llena.
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		p1.Team[name] = &game.Pokemon{Name: name, Fainted: true}
+	}
+	n := node{sides: [2]side{newSide(dex, state, p1, rng, false), newSide(dex, state, p2, rng, false)}}
+	if got, want := evaluate(n), 1.0/6-1; got < want-1e-9 || got > want+1e-9 {
+		t.Errorf("evaluate = %v, se esperaba %v", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package sim
+
+import (
+	"math"
+	"showdown-analizer/calc"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/infer"
+)
+
+// stat es el valor del stat de poke que usa el simulador: el exacto si se
+// conoce, si no el del reparto más probable o el punto medio del rango
+// acotado hasta ahora.
+func stat(dex *data.Dex, format string, poke *game.Pokemon, name string) int {
+	if name == "hp" && poke.Stats != nil && poke.MaxHP > 0 {
+		return poke.MaxHP
+	}
+	r, ok := infer.Likely(dex, format, poke, name)
+	if !ok {
+		return 0
+	}
+	return (r.Min + r.Max) / 2
+}
+
+// toUnits pasa daño real a las unidades de vida con que se sigue a poke,
+// que para el rival suelen ser porcentajes.
+func toUnits(dex *data.Dex, format string, poke *game.Pokemon, damage int) int {
+	maxHP := stat(dex, format, poke, "hp")
+	if maxHP <= 0 || poke.MaxHP <= 0 || damage <= 0 {
+		return damage
+	}
+	return int(math.Ceil(float64(damage) * float64(poke.MaxHP) / float64(maxHP)))
+}
+
+// hit calcula el daño de move de user a target en unidades de vida de
+// target con una tirada concreta.
+func hit(dex *data.Dex, state *game.BattleState, user, target *game.Pokemon, move data.MoveData, crit bool, roll int) (int, float64) {
+	eff := dex.Effectiveness(move.Type, target.Type, data.EffectivenessOptions{
+		MoveID:                data.ToID(move.Name),
+		Scrappy:               user.Ability == "Scrappy" || user.Ability == "Mind's Eye",
+		DefenderTerastallized: target.Terastallized,
+	})
+	if eff == 0 || move.Power <= 0 {
+		return 0, eff
+	}
+
+	physical := move.Category == "Physical"
+	offStat, defStat := "spa", "spd"
+	if physical {
+		offStat, defStat = "atk", "def"
+	}
+	// Un crítico ignora los boosts negativos del atacante y los positivos
+	// del defensor.
+	atkBoost, defBoost := user.Boosts[offStat], target.Boosts[defStat]
+	if crit {
+		atkBoost, defBoost = max(atkBoost, 0), min(defBoost, 0)
+	}
+	attack := float64(stat(dex, state.Format, user, offStat)) * calc.BoostMultiplier(atkBoost)
+	defense := float64(stat(dex, state.Format, target, defStat)) * calc.BoostMultiplier(defBoost)
+
+	modifier := 1.0
+	switch data.ToID(user.Item) {
+	case "lifeorb":
+		modifier = 1.3
+	case "choiceband":
+		if physical {
+			attack *= 1.5
+		}
+	case "choicespecs":
+		if !physical {
+			attack *= 1.5
+		}
+	}
+	switch data.ToID(target.Item) {
+	case "eviolite":
+		defense *= 1.5
+	case "assaultvest":
+		if !physical {
+			defense *= 1.5
+		}
+	}
+
+	stab := user.STAB(move.Type)
+	level := user.Level
+	if level == 0 {
+		level = 100
+	}
+	damage := calc.DamageRoll(calc.DamageInput{
+		Level: level, Power: move.Power,
+		Attack: int(attack), Defense: int(defense),
+		STAB: stab, Effectiveness: eff, Modifier: modifier,
+		Weather: calc.WeatherModifier(state.Weather, move.Type),
+		Crit:    crit, Burned: physical && user.Status == "brn" && user.Ability != "Guts",
+	}, roll)
+	return toUnits(dex, state.Format, target, damage), eff
+}
+
+// DamageRange devuelve el daño mínimo y máximo sin crítico de move de user
+// a target, en las unidades de vida de target.
+func DamageRange(dex *data.Dex, state *game.BattleState, user, target *game.Pokemon, moveName string) (int, int, bool) {
+	move, ok := dex.GetMove(moveName)
+	if !ok || move.Category == "Status" || move.Power <= 0 {
+		return 0, 0, false
+	}
+	lo, _ := hit(dex, state, user, target, move, false, 85)
+	hi, _ := hit(dex, state, user, target, move, false, 100)
+	return lo, hi, true
+}
@@ -0,0 +1,30 @@
+package sim
+
+// PRNG es el generador congruencial lineal de 64 bits que usaba Showdown.
+// Sirve para que Step sea determinista con una semilla; no reproduce
+// batallas reales, porque el servidor ya usa otro generador y los replays
+// no traen la semilla.
+type PRNG struct {
+	seed uint64
+}
+
+// NewPRNG arma el generador con una semilla en el formato de Showdown,
+// cuatro enteros de 16 bits del más al menos significativo.
+func NewPRNG(seed [4]uint16) *PRNG {
+	return &PRNG{seed: uint64(seed[0])<<48 | uint64(seed[1])<<32 | uint64(seed[2])<<16 | uint64(seed[3])}
+}
+
+func (r *PRNG) next() uint32 {
+	r.seed = r.seed*0x5D588B656C078965 + 0x269EC3
+	return uint32(r.seed >> 32)
+}
+
+// Random devuelve un entero en [0, n).
+func (r *PRNG) Random(n int) int {
+	return int(uint64(r.next()) * uint64(n) >> 32)
+}
+
+// Chance es true con probabilidad num/den.
+func (r *PRNG) Chance(num, den int) bool {
+	return r.Random(den) < num
+}
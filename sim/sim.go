@@ -0,0 +1,552 @@
+// Package sim aplica un turno de elecciones a un BattleState con un
+// subconjunto de las reglas de Showdown: daño, precisión, críticos, efectos
+// secundarios, estados, clima, hazards y cambios. Lo que no modela (U-turn,
+// habilidades, la mayoría de los objetos) simplemente no ocurre.
+package sim
+
+import (
+	"fmt"
+	"math"
+	"showdown-analizer/analysis"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/speed"
+	"strings"
+)
+
+// Choice es lo que elige un jugador en un turno: un movimiento, un cambio o
+// nada (por ejemplo cuando sólo el rival tiene que reemplazar a un
+// debilitado).
+type Choice struct {
+	Move   string `json:"move,omitempty"`
+	Switch string `json:"switch,omitempty"`
+}
+
+var sides = [2]string{"p1", "p2"}
+
+type battle struct {
+	dex      *data.Dex
+	state    *game.BattleState
+	rng      *PRNG
+	log      []string
+	flinched map[*game.Pokemon]bool
+}
+
+// Step aplica choices (de p1 y p2) a una copia de state y devuelve el estado
+// resultante con las líneas de protocolo que lo describen. state no se
+// modifica. Si al empezar algún activo está debilitado el paso sólo hace
+// los reemplazos.
+func Step(dex *data.Dex, state *game.BattleState, choices [2]Choice, rng *PRNG) (*game.BattleState, []string) {
+	b := &battle{dex: dex, state: state.Clone(), rng: rng, flinched: map[*game.Pokemon]bool{}}
+	for _, player := range b.state.Players {
+		for _, poke := range player.Team {
+			if poke.MaxHP == 0 {
+				poke.HP, poke.MaxHP = 100, 100
+			}
+		}
+	}
+
+	replacing := false
+	for _, id := range sides {
+		if p := b.state.Players[id]; p != nil && p.Active != nil && p.Active.Fainted {
+			replacing = true
+		}
+	}
+
+	for i, id := range sides {
+		if choices[i].Switch != "" {
+			b.switchIn(id, choices[i].Switch)
+		}
+	}
+	if !replacing {
+		for _, i := range b.moveOrder(choices) {
+			if choices[i].Move != "" && choices[i].Switch == "" {
+				b.useMove(sides[i], choices[i].Move)
+			}
+		}
+		b.residual()
+	}
+
+	if !b.needsReplacement() {
+		b.state.Turn++
+		b.state.TurnMoves = nil
+		b.emit("|turn|%d", b.state.Turn)
+	}
+	return b.state, b.log
+}
+
+func (b *battle) emit(format string, args ...any) {
+	b.log = append(b.log, fmt.Sprintf(format, args...))
+}
+
+func (b *battle) ident(id string, poke *game.Pokemon) string {
+	return id + "a: " + poke.Name
+}
+
+func hpText(poke *game.Pokemon) string {
+	if poke.HP <= 0 {
+		return "0 fnt"
+	}
+	text := fmt.Sprintf("%d/%d", poke.HP, poke.MaxHP)
+	if poke.Status != "" {
+		text += " " + poke.Status
+	}
+	return text
+}
+
+func (b *battle) needsReplacement() bool {
+	for _, id := range sides {
+		player := b.state.Players[id]
+		if player == nil || player.Active == nil || !player.Active.Fainted {
+			continue
+		}
+		for _, poke := range player.Team {
+			if !poke.Fainted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *battle) foe(id string) (string, *game.Player) {
+	if id == "p1" {
+		return "p2", b.state.Players["p2"]
+	}
+	return "p1", b.state.Players["p1"]
+}
+
+func (b *battle) switchIn(id, name string) {
+	player := b.state.Players[id]
+	if player == nil {
+		return
+	}
+	poke := player.Team[name]
+	if poke == nil || poke.Fainted || poke == player.Active {
+		return
+	}
+	if out := player.Active; out != nil {
+		out.Boosts = map[string]int{}
+		if out.Status == "tox" {
+			out.StatusTurns = 0
+		}
+	}
+	player.Active = poke
+	poke.Preview = false
+	details := poke.Species
+	if poke.Level != 0 && poke.Level != 100 {
+		details += fmt.Sprintf(", L%d", poke.Level)
+	}
+	b.emit("|switch|%s|%s|%s", b.ident(id, poke), details, hpText(poke))
+
+	if data.ToID(poke.Item) == "heavydutyboots" {
+		return
+	}
+	if player.SideConditions["Stealth Rock"] > 0 && poke.Ability != "Magic Guard" {
+		eff := b.dex.Effectiveness("Rock", poke.Type, data.EffectivenessOptions{})
+		// Como en Showdown, el daño residual se trunca y es al menos 1.
+		b.damage(id, poke, max(1, int(eff*float64(poke.MaxHP)/8)), "[from] Stealth Rock")
+	}
+	if poke.Fainted || !analysis.Grounded(poke) {
+		return
+	}
+	if layers := player.SideConditions["Spikes"]; layers > 0 && poke.Ability != "Magic Guard" {
+		// 1/8, 1/6 y 1/4 según las capas.
+		div := []int{8, 6, 4}[min(layers, 3)-1]
+		b.damage(id, poke, max(1, poke.MaxHP/div), "[from] Spikes")
+	}
+	if poke.Fainted {
+		return
+	}
+	if layers := player.SideConditions["Toxic Spikes"]; layers > 0 {
+		if hasType(poke, "Poison") {
+			delete(player.SideConditions, "Toxic Spikes")
+			b.emit("|-sideend|%s: %s|move: Toxic Spikes|[of] %s", id, player.Name, b.ident(id, poke))
+		} else if layers >= 2 {
+			b.setStatus(id, poke, "tox")
+		} else {
+			b.setStatus(id, poke, "psn")
+		}
+	}
+	if player.SideConditions["Sticky Web"] > 0 {
+		b.boost(id, poke, map[string]int{"spe": -1})
+	}
+}
+
+// speedOf es la Speed efectiva de poke; Choice Scarf se cuenta sólo si se
+// conoce el objeto.
+func (b *battle) speedOf(id string) float64 {
+	player := b.state.Players[id]
+	poke := player.Active
+	s := float64(stat(b.dex, b.state.Format, poke, "spe")) * speed.Multiplier(b.dex, b.state, player, poke)
+	if data.ToID(poke.Item) == "choicescarf" {
+		s *= 1.5
+	}
+	return s
+}
+
+// moveOrder ordena a los jugadores por prioridad del movimiento y después
+// por Speed, con Trick Room y empates al azar como en Showdown.
+func (b *battle) moveOrder(choices [2]Choice) []int {
+	for _, id := range sides {
+		if p := b.state.Players[id]; p == nil || p.Active == nil {
+			return []int{0, 1}
+		}
+	}
+	prio := [2]int{}
+	for i := range sides {
+		prio[i] = b.dex.GetMovePriority(choices[i].Move)
+	}
+	if prio[0] != prio[1] {
+		if prio[0] > prio[1] {
+			return []int{0, 1}
+		}
+		return []int{1, 0}
+	}
+	s1, s2 := b.speedOf("p1"), b.speedOf("p2")
+	if speed.TrickRoom(b.state) {
+		s1, s2 = s2, s1
+	}
+	if s1 > s2 || (s1 == s2 && b.rng.Random(2) == 0) {
+		return []int{0, 1}
+	}
+	return []int{1, 0}
+}
+
+// canMove resuelve parálisis, sueño, congelamiento y retroceso.
+func (b *battle) canMove(id string, poke *game.Pokemon) bool {
+	if b.flinched[poke] {
+		b.emit("|cant|%s|flinch", b.ident(id, poke))
+		return false
+	}
+	switch poke.Status {
+	case "slp":
+		// Se duerme de 1 a 3 turnos: cada turno despierta con 1/3.
+		if poke.StatusTurns >= 3 || b.rng.Chance(1, 3) {
+			b.cureStatus(id, poke)
+			return true
+		}
+		b.emit("|cant|%s|slp", b.ident(id, poke))
+		return false
+	case "frz":
+		if b.rng.Chance(1, 5) {
+			b.cureStatus(id, poke)
+			return true
+		}
+		b.emit("|cant|%s|frz", b.ident(id, poke))
+		return false
+	case "par":
+		if b.rng.Chance(1, 4) {
+			b.emit("|cant|%s|par", b.ident(id, poke))
+			return false
+		}
+	}
+	return true
+}
+
+func (b *battle) useMove(id, moveName string) {
+	player := b.state.Players[id]
+	user := player.Active
+	if user == nil || user.Fainted {
+		return
+	}
+	move, ok := b.dex.GetMove(moveName)
+	if !ok || !b.canMove(id, user) {
+		return
+	}
+	for i := range user.Moves {
+		if strings.EqualFold(user.Moves[i].Name, move.Name) {
+			user.Moves[i].PPUsed++
+		}
+	}
+
+	foeID, foePlayer := b.foe(id)
+	if foePlayer == nil {
+		return
+	}
+	target := foePlayer.Active
+	selfTarget := move.Target == "self" || move.Target == "allySide" || move.Target == "allies"
+	switch {
+	case selfTarget || move.Target == "all":
+		// Los movimientos de campo, como los de clima, se anuncian sobre
+		// el usuario.
+		b.emit("|move|%s|%s|%s", b.ident(id, user), move.Name, b.ident(id, user))
+	case target == nil || target.Fainted:
+		b.emit("|move|%s|%s|", b.ident(id, user), move.Name)
+	default:
+		b.emit("|move|%s|%s|%s", b.ident(id, user), move.Name, b.ident(foeID, target))
+	}
+	b.state.TurnMoves = append(b.state.TurnMoves, game.MoveEvent{Player: id, Pokemon: user, Move: move.Name, TargetPlayer: foeID, Target: target})
+
+	needsTarget := move.Category != "Status" || move.Target == "normal" || move.Target == "any"
+	if needsTarget && (target == nil || target.Fainted) {
+		b.emit("|-notarget|%s", b.ident(id, user))
+		return
+	}
+	if needsTarget && move.Accuracy > 0 && !b.rng.Chance(move.Accuracy, 100) {
+		b.emit("|-miss|%s|%s", b.ident(id, user), b.ident(foeID, target))
+		return
+	}
+
+	if move.Category != "Status" {
+		b.attack(id, user, foeID, target, move)
+		return
+	}
+
+	switch {
+	case move.Status != "":
+		if data.ToID(move.Name) == "thunderwave" && b.dex.Effectiveness(move.Type, target.Type, data.EffectivenessOptions{}) == 0 {
+			b.emit("|-immune|%s", b.ident(foeID, target))
+			return
+		}
+		if target.Status != "" {
+			b.emit("|-fail|%s|%s", b.ident(foeID, target), move.Status)
+			return
+		}
+		b.setStatus(foeID, target, move.Status)
+	case move.Weather != "":
+		weather := protocolWeather(move.Weather)
+		b.state.Weather = weather
+		b.state.WeatherTurns = 5
+		b.emit("|-weather|%s", weather)
+	case move.SideCondition != "":
+		sideID, side := foeID, foePlayer
+		if move.Target == "allySide" {
+			sideID, side = id, player
+		}
+		b.addSideCondition(id, user, sideID, side, b.dex.GetMoveName(move.SideCondition))
+	}
+	if len(move.Boosts) > 0 {
+		if selfTarget {
+			b.boost(id, user, move.Boosts)
+		} else {
+			b.boost(foeID, target, move.Boosts)
+		}
+	}
+	if move.HealFraction[1] > 0 {
+		b.heal(id, user, user.MaxHP*move.HealFraction[0]/move.HealFraction[1], "")
+	}
+}
+
+func (b *battle) attack(id string, user *game.Pokemon, foeID string, target *game.Pokemon, move data.MoveData) {
+	// Tasas de crítico de gen 7 en adelante por nivel de critRatio.
+	critRate := []int{24, 8, 2, 1}
+	stage := min(max(move.CritRatio, 1), len(critRate)) - 1
+	crit := b.rng.Chance(1, critRate[stage])
+	roll := 100 - b.rng.Random(16)
+
+	damage, eff := hit(b.dex, b.state, user, target, move, crit, roll)
+	if eff == 0 {
+		b.emit("|-immune|%s", b.ident(foeID, target))
+		return
+	}
+	if crit {
+		b.emit("|-crit|%s", b.ident(foeID, target))
+	}
+	switch {
+	case eff > 1:
+		b.emit("|-supereffective|%s", b.ident(foeID, target))
+	case eff < 1:
+		b.emit("|-resisted|%s", b.ident(foeID, target))
+	}
+	dealt := float64(min(damage, target.HP))
+	if (target.MaxHP == 100) != (user.MaxHP == 100) {
+		// Una vida es exacta y la otra un porcentaje: el daño se pasa a la
+		// escala de user como fracción.
+		dealt = dealt / float64(target.MaxHP) * float64(user.MaxHP)
+	}
+	b.damage(foeID, target, damage, "")
+
+	// Showdown redondea el drenaje y el retroceso (Math.round); el
+	// retroceso es al menos 1.
+	if move.Drain[1] > 0 {
+		b.heal(id, user, int(math.Round(dealt*float64(move.Drain[0])/float64(move.Drain[1]))), "[from] drain")
+	}
+	if move.Recoil[1] > 0 {
+		b.damage(id, user, max(1, int(math.Round(dealt*float64(move.Recoil[0])/float64(move.Recoil[1])))), "[from] Recoil")
+	}
+	if data.ToID(user.Item) == "lifeorb" && !user.Fainted {
+		b.damage(id, user, max(1, user.MaxHP/10), "[from] item: Life Orb")
+	}
+
+	for _, sec := range move.Secondaries {
+		if !b.rng.Chance(sec.Chance, 100) {
+			continue
+		}
+		if !target.Fainted {
+			if sec.Status != "" {
+				b.setStatus(foeID, target, sec.Status)
+			}
+			if len(sec.Boosts) > 0 {
+				b.boost(foeID, target, sec.Boosts)
+			}
+			if sec.Flinch {
+				b.flinched[target] = true
+			}
+		}
+		if len(sec.SelfBoosts) > 0 && !user.Fainted {
+			b.boost(id, user, sec.SelfBoosts)
+		}
+	}
+	if len(move.SelfBoosts) > 0 && !user.Fainted {
+		b.boost(id, user, move.SelfBoosts)
+	}
+}
+
+func (b *battle) damage(id string, poke *game.Pokemon, amount int, from string) {
+	if amount <= 0 || poke.Fainted {
+		return
+	}
+	poke.HP = max(0, poke.HP-amount)
+	line := fmt.Sprintf("|-damage|%s|%s", b.ident(id, poke), hpText(poke))
+	if from != "" {
+		line += "|" + from
+	}
+	b.log = append(b.log, line)
+	if poke.HP == 0 {
+		poke.Fainted = true
+		poke.Status = ""
+		b.emit("|faint|%s", b.ident(id, poke))
+	}
+}
+
+func (b *battle) heal(id string, poke *game.Pokemon, amount int, from string) {
+	if amount <= 0 || poke.Fainted || poke.HP >= poke.MaxHP {
+		return
+	}
+	poke.HP = min(poke.MaxHP, poke.HP+amount)
+	line := fmt.Sprintf("|-heal|%s|%s", b.ident(id, poke), hpText(poke))
+	if from != "" {
+		line += "|" + from
+	}
+	b.log = append(b.log, line)
+}
+
+func hasType(poke *game.Pokemon, t string) bool {
+	for _, pt := range poke.Type {
+		if pt == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *battle) setStatus(id string, poke *game.Pokemon, status string) {
+	if poke.Status != "" || poke.Fainted {
+		return
+	}
+	immune := map[string][]string{
+		"par": {"Electric"}, "brn": {"Fire"}, "frz": {"Ice"},
+		"psn": {"Poison", "Steel"}, "tox": {"Poison", "Steel"},
+	}
+	for _, t := range immune[status] {
+		if hasType(poke, t) {
+			return
+		}
+	}
+	poke.Status = status
+	poke.StatusTurns = 0
+	b.emit("|-status|%s|%s", b.ident(id, poke), status)
+}
+
+func (b *battle) cureStatus(id string, poke *game.Pokemon) {
+	b.emit("|-curestatus|%s|%s|[msg]", b.ident(id, poke), poke.Status)
+	poke.Status = ""
+	poke.StatusTurns = 0
+}
+
+func (b *battle) boost(id string, poke *game.Pokemon, boosts map[string]int) {
+	if poke.Boosts == nil {
+		poke.Boosts = map[string]int{}
+	}
+	for stat, amount := range boosts {
+		next := max(-6, min(6, poke.Boosts[stat]+amount))
+		delta := next - poke.Boosts[stat]
+		poke.Boosts[stat] = next
+		switch {
+		case delta > 0:
+			b.emit("|-boost|%s|%s|%d", b.ident(id, poke), stat, delta)
+		case delta < 0:
+			b.emit("|-unboost|%s|%s|%d", b.ident(id, poke), stat, -delta)
+		}
+	}
+}
+
+// Capas máximas de cada condición de lado; el resto admite una.
+var maxLayers = map[string]int{"Spikes": 3, "Toxic Spikes": 2}
+
+// addSideCondition pone name en el lado sideID; si ya está al máximo falla
+// el movimiento de user.
+func (b *battle) addSideCondition(id string, user *game.Pokemon, sideID string, player *game.Player, name string) {
+	if player.SideConditions == nil {
+		player.SideConditions = map[string]int{}
+	}
+	if player.SideConditions[name] >= max(maxLayers[name], 1) {
+		b.emit("|-fail|%s", b.ident(id, user))
+		return
+	}
+	player.SideConditions[name]++
+	b.emit("|-sidestart|%s: %s|move: %s", sideID, player.Name, name)
+}
+
+// Los datos de movimientos traen el ID del clima ("sunnyday"); el estado y
+// el protocolo usan el nombre que manda |-weather|.
+var weatherNames = map[string]string{
+	"sunnyday":  "SunnyDay",
+	"raindance": "RainDance",
+	"sandstorm": "Sandstorm",
+	"hail":      "Hail",
+	"snowscape": "Snow",
+	"snow":      "Snow",
+}
+
+func protocolWeather(id string) string {
+	if name, ok := weatherNames[data.ToID(id)]; ok {
+		return name
+	}
+	return id
+}
+
+// residual aplica los efectos de fin de turno: clima, quemadura, veneno y
+// Leftovers.
+func (b *battle) residual() {
+	if b.state.WeatherTurns > 0 {
+		b.state.WeatherTurns--
+		if b.state.WeatherTurns == 0 {
+			b.state.Weather = ""
+			b.emit("|-weather|none")
+		}
+	}
+	for _, id := range sides {
+		player := b.state.Players[id]
+		if player == nil || player.Active == nil || player.Active.Fainted {
+			continue
+		}
+		poke := player.Active
+		switch {
+		case b.state.Weather == "Sandstorm" && !hasType(poke, "Rock") && !hasType(poke, "Ground") && !hasType(poke, "Steel"):
+			b.damage(id, poke, max(1, poke.MaxHP/16), "[from] Sandstorm")
+		case b.state.Weather == "Hail" && !hasType(poke, "Ice"):
+			b.damage(id, poke, max(1, poke.MaxHP/16), "[from] Hail")
+		}
+		switch poke.Status {
+		case "brn":
+			div := 16
+			if b.dex.Gen != 0 && b.dex.Gen < 7 {
+				div = 8
+			}
+			b.damage(id, poke, max(1, poke.MaxHP/div), "[from] brn")
+		case "psn":
+			b.damage(id, poke, max(1, poke.MaxHP/8), "[from] psn")
+		case "tox":
+			b.damage(id, poke, max(1, poke.MaxHP*min(poke.StatusTurns+1, 15)/16), "[from] psn")
+		}
+		if data.ToID(poke.Item) == "leftovers" {
+			b.heal(id, poke, max(1, poke.MaxHP/16), "[from] item: Leftovers")
+		}
+		if poke.Status != "" {
+			poke.StatusTurns++
+		}
+	}
+	b.emit("|upkeep")
+}
@@ -0,0 +1,232 @@
+package sim
+
+import (
+	"showdown-analizer/data"
+	"showdown-analizer/data/datatest"
+	"showdown-analizer/game"
+	"showdown-analizer/parser"
+	"strings"
+	"testing"
+)
+
+// Comienzo de una batalla de gen 9 OU hasta el turno 1, con las líneas tal
+// como las manda el servidor y Volcarona ya vista en el banco de p2.
+const ouStart = `|player|p1|Alice|1
+|player|p2|Bob|2
+|gen|9
+|tier|[Gen 9] OU
+|switch|p1a: Garchomp|Garchomp, M|100/100
+|switch|p2a: Volcarona|Volcarona, F|100/100
+|switch|p2a: Heatran|Heatran, F|100/100
+|turn|1`
+
+func stateFromLog(dex *data.Dex, log string) *game.BattleState {
+	state := game.NewBattleState()
+	for _, line := range strings.Split(log, "\n") {
+		parser.ProcessLine(dex, state, line)
+	}
+	return state
+}
+
+// containsInOrder indica si cada línea de want es prefijo de una línea de
+// got, en el mismo orden. Los prefijos permiten no fijar la vida exacta.
+func containsInOrder(got, want []string) bool {
+	i := 0
+	for _, line := range got {
+		if i < len(want) && strings.HasPrefix(line, want[i]) {
+			i++
+		}
+	}
+	return i == len(want)
+}
+
+func TestStep(t *testing.T) {
+	tests := []struct {
+		name    string
+		gen     int
+		log     string
+		prepare func(*game.BattleState)
+		choices [2]Choice
+		want    []string
+		check   func(*game.BattleState) bool
+	}{
+		{
+			name:    "stealth rock",
+			log:     ouStart,
+			choices: [2]Choice{{Move: "Stealth Rock"}, {}},
+			want:    []string{"|move|p1a: Garchomp|Stealth Rock|p2a: Heatran", "|-sidestart|p2: Bob|move: Stealth Rock", "|upkeep", "|turn|2"},
+			check:   func(s *game.BattleState) bool { return s.Players["p2"].SideConditions["Stealth Rock"] == 1 },
+		},
+		{
+			name:    "stealth rock repetido",
+			log:     ouStart + "\n|-sidestart|p2: Bob|move: Stealth Rock",
+			choices: [2]Choice{{Move: "Stealth Rock"}, {}},
+			want:    []string{"|move|p1a: Garchomp|Stealth Rock|p2a: Heatran", "|-fail|p1a: Garchomp"},
+			check:   func(s *game.BattleState) bool { return s.Players["p2"].SideConditions["Stealth Rock"] == 1 },
+		},
+		{
+			name:    "entrar con stealth rock",
+			log:     ouStart + "\n|-sidestart|p2: Bob|move: Stealth Rock",
+			choices: [2]Choice{{}, {Switch: "Volcarona"}},
+			want:    []string{"|switch|p2a: Volcarona|", "|-damage|p2a: Volcarona|50/100|[from] Stealth Rock"},
+			check:   func(s *game.BattleState) bool { return s.Players["p2"].Active.HP == 50 },
+		},
+		{
+			name:    "sunny day",
+			log:     ouStart,
+			choices: [2]Choice{{}, {Move: "Sunny Day"}},
+			want:    []string{"|move|p2a: Heatran|Sunny Day|p2a: Heatran", "|-weather|SunnyDay"},
+			check:   func(s *game.BattleState) bool { return s.Weather == "SunnyDay" && s.WeatherTurns == 4 },
+		},
+		{
+			name:    "snowscape",
+			log:     ouStart,
+			choices: [2]Choice{{}, {Move: "Snowscape"}},
+			want:    []string{"|move|p2a: Heatran|Snowscape|p2a: Heatran", "|-weather|Snow"},
+			check:   func(s *game.BattleState) bool { return s.Weather == "Snow" },
+		},
+		{
+			name:    "hail quita vida al final del turno",
+			gen:     8,
+			log:     strings.Replace(ouStart, "|gen|9", "|gen|8", 1),
+			choices: [2]Choice{{Move: "Hail"}, {}},
+			want:    []string{"|move|p1a: Garchomp|Hail|p1a: Garchomp", "|-weather|Hail", "|-damage|p1a: Garchomp|94/100|[from] Hail", "|-damage|p2a: Heatran|94/100|[from] Hail", "|upkeep"},
+			check:   func(s *game.BattleState) bool { return s.Weather == "Hail" },
+		},
+		{
+			name:    "el más rápido debilita primero",
+			log:     ouStart,
+			choices: [2]Choice{{Move: "Earthquake"}, {Move: "Magma Storm"}},
+			want:    []string{"|move|p1a: Garchomp|Earthquake|p2a: Heatran", "|-supereffective|p2a: Heatran", "|-damage|p2a: Heatran|0 fnt", "|faint|p2a: Heatran"},
+			check:   func(s *game.BattleState) bool { return len(s.TurnMoves) == 1 },
+		},
+		{
+			name:    "hazard fallido sin rival activo",
+			log:     ouStart + "\n|-sidestart|p2: Bob|move: Stealth Rock",
+			prepare: func(s *game.BattleState) { s.Players["p2"].Active = nil },
+			choices: [2]Choice{{Move: "Stealth Rock"}, {}},
+			want:    []string{"|move|p1a: Garchomp|Stealth Rock|", "|-fail|p1a: Garchomp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := tt.gen
+			if gen == 0 {
+				gen = 9
+			}
+			dex := datatest.Dex(t, gen)
+			state := stateFromLog(dex, tt.log)
+			if tt.prepare != nil {
+				tt.prepare(state)
+			}
+			before := state.Snapshot()
+
+			next, lines := Step(dex, state, tt.choices, NewPRNG([4]uint16{1, 2, 3, 4}))
+			if !containsInOrder(lines, tt.want) {
+				t.Errorf("faltan líneas:\nse esperaba %q\nse obtuvo  %q", tt.want, lines)
+			}
+			if tt.check != nil && !tt.check(next) {
+				t.Errorf("el estado resultante no es el esperado: %q", lines)
+			}
+			if d := game.Diff(before, state); len(d) != 0 {
+				t.Errorf("Step modificó el estado original: %v", d)
+			}
+		})
+	}
+}
+
+func TestStepWeatherBoostsDamage(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	state := stateFromLog(dex, ouStart)
+	heatran, garchomp := state.Players["p2"].Active, state.Players["p1"].Active
+	_, dry, _ := DamageRange(dex, state, heatran, garchomp, "Flamethrower")
+
+	sunny, _ := Step(dex, state, [2]Choice{{}, {Move: "Sunny Day"}}, NewPRNG([4]uint16{1, 2, 3, 4}))
+	_, sun, _ := DamageRange(dex, sunny, sunny.Players["p2"].Active, sunny.Players["p1"].Active, "Flamethrower")
+	if sun <= dry {
+		t.Errorf("Flamethrower con sol = %d, sin sol = %d: el sol no se aplicó", sun, dry)
+	}
+}
+
+func TestPRNG(t *testing.T) {
+	a, b := NewPRNG([4]uint16{1, 2, 3, 4}), NewPRNG([4]uint16{1, 2, 3, 4})
+	for i := 0; i < 100; i++ {
+		x, y := a.Random(16), b.Random(16)
+		if x != y {
+			t.Fatalf("misma semilla, tirada %d distinta: %d y %d", i, x, y)
+		}
+		if x < 0 || x >= 16 {
+			t.Fatalf("Random(16) = %d fuera de rango", x)
+		}
+	}
+	if NewPRNG([4]uint16{4, 3, 2, 1}).Random(1<<16) == NewPRNG([4]uint16{1, 2, 3, 4}).Random(1<<16) {
+		t.Error("semillas distintas dieron la misma primera tirada")
+	}
+}
+
+// Comienzo con vidas exactas, para fijar el redondeo de Showdown.
+const exactStart = `|player|p1|Alice|1
+|player|p2|Bob|2
+|gen|9
+|tier|[Gen 9] OU
+|switch|p1a: Garchomp|Garchomp, M|357/357
+|switch|p2a: Volcarona|Volcarona, F|311/311
+|switch|p2a: Gholdengo|Gholdengo|301/301
+|switch|p2a: Heatran|Heatran, F|200/311
+|turn|1`
+
+func TestStepExactHP(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     string
+		prepare func(*game.BattleState)
+		choices [2]Choice
+		// Vida final de los Pokémon de cada lado.
+		p1, p2 int
+	}{
+		{
+			// 311 * 4 / 8 = 155.5 se trunca a 155.
+			name:    "stealth rock trunca",
+			log:     exactStart + "\n|-sidestart|p2: Bob|move: Stealth Rock",
+			choices: [2]Choice{{}, {Switch: "Volcarona"}},
+			p1:      357, p2: 156,
+		},
+		{
+			// 301 / 8 = 37.6 se trunca a 37.
+			name:    "spikes trunca",
+			log:     exactStart + "\n|-sidestart|p2: Bob|Spikes",
+			choices: [2]Choice{{}, {Switch: "Gholdengo"}},
+			p1:      357, p2: 264,
+		},
+		{
+			// Debilita a Heatran con 46 de daño: 46 / 3 = 15.3 se redondea a 15.
+			name:    "retroceso redondea",
+			log:     exactStart,
+			prepare: func(s *game.BattleState) { s.Players["p2"].Active.HP = 46 },
+			choices: [2]Choice{{Move: "Double-Edge"}, {}},
+			p1:      342, p2: 0,
+		},
+		{
+			// 3 de daño drenan 3 * 3 / 4 = 2.25, que se redondea a 2.
+			name:    "drenaje redondea",
+			log:     exactStart,
+			prepare: func(s *game.BattleState) { s.Players["p1"].Active.HP = 3 },
+			choices: [2]Choice{{}, {Move: "Oblivion Wing"}},
+			p1:      0, p2: 202,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dex := datatest.Dex(t, 9)
+			state := stateFromLog(dex, tt.log)
+			if tt.prepare != nil {
+				tt.prepare(state)
+			}
+			next, lines := Step(dex, state, tt.choices, NewPRNG([4]uint16{1, 2, 3, 4}))
+			p1, p2 := next.Players["p1"].Active.HP, next.Players["p2"].Active.HP
+			if p1 != tt.p1 || p2 != tt.p2 {
+				t.Errorf("vidas %d y %d, se esperaba %d y %d: %q", p1, p2, tt.p1, tt.p2, lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package speed
+
+import (
+	"math"
+	"showdown-analizer/calc"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"strings"
+)
+
+type Range struct {
+	Min int
+	Max int
+}
+
+func (r Range) Known() bool {
+	return r.Max > 0
+}
+
+func (r Range) Exact() bool {
+	return r.Known() && r.Min == r.Max
+}
+
+func (r Range) scale(f float64) Range {
+	return Range{Min: int(float64(r.Min) * f), Max: int(float64(r.Max) * f)}
+}
+
+// StatRange acota la Speed sin modificadores de poke según su especie y nivel.
+// En Random Battle los EVs, IVs y naturaleza son fijos y el valor es exacto;
+// en otros formatos va de 0 EVs con naturaleza negativa a 252 con positiva.
+func StatRange(dex *data.Dex, format string, poke *game.Pokemon) Range {
+	if spe, ok := poke.Stats["spe"]; ok {
+		return Range{Min: spe, Max: spe}
+	}
+	species := poke.Species
+	if species == "" {
+		species = poke.Name
+	}
+	base, ok := dex.GetBaseStats(species)["spe"]
+	if !ok {
+		return Range{}
+	}
+	level := poke.Level
+	if level == 0 {
+		level = 100
+	}
+	if strings.Contains(format, "random") {
+		s := calc.Stat(base, calc.MaxIV, calc.RandomBattleEV, level, 1)
+		return Range{Min: s, Max: s}
+	}
+	return Range{
+		Min: calc.Stat(base, 0, 0, level, 0.9),
+		Max: calc.Stat(base, calc.MaxIV, calc.MaxEV, level, 1.1),
+	}
+}
+
+// baseRange combina el rango teórico con lo observado. El resultado incluye
+// el efecto del objeto (Choice Scarf) pero no el de boosts ni del campo.
+func baseRange(dex *data.Dex, format string, poke *game.Pokemon) Range {
+	r := StatRange(dex, format, poke)
+	if !r.Known() {
+		return r
+	}
+	if data.ToID(poke.Item) == "choicescarf" || poke.ScarfSuspected {
+		r = r.scale(1.5)
+	}
+	if poke.SpeedLower > r.Min {
+		r.Min = poke.SpeedLower
+	}
+	if poke.SpeedUpper > 0 && poke.SpeedUpper < r.Max {
+		r.Max = poke.SpeedUpper
+	}
+	if r.Min > r.Max {
+		r.Min = r.Max
+	}
+	return r
+}
+
+// Habilidades que duplican la Speed con un clima o terreno, y los climas y
+// terrenos que las activan.
+var fieldAbilities = map[string][]string{
+	"chlorophyll": {"SunnyDay", "DesolateLand"},
+	"swiftswim":   {"RainDance", "PrimordialSea"},
+	"sandrush":    {"Sandstorm"},
+	"slushrush":   {"Hail", "Snow"},
+	"surgesurfer": {"Electric Terrain"},
+}
+
+// fieldActive indica si hay alguno de los climas o terrenos de fields.
+func fieldActive(state *game.BattleState, fields []string) bool {
+	for _, f := range fields {
+		if state.Weather == f {
+			return true
+		}
+		for effect := range state.FieldEffects {
+			if strings.Contains(effect, f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// possibleAbilities devuelve la habilidad de poke si se reveló o, si no,
+// todas las que puede tener su especie.
+func possibleAbilities(dex *data.Dex, poke *game.Pokemon) []string {
+	if poke.Ability != "" {
+		return []string{poke.Ability}
+	}
+	species := poke.Species
+	if species == "" {
+		species = poke.Name
+	}
+	return dex.GetPokemonAbilities(species)
+}
+
+// Multiplier es el factor de Speed conocido en este momento: boosts,
+// parálisis, Tailwind del lado del jugador, Iron Ball y Macho Brace
+// revelados, Protosynthesis o Quark Drive sobre la Speed y las habilidades
+// de clima reveladas con su clima activo.
+func Multiplier(dex *data.Dex, state *game.BattleState, player *game.Player, poke *game.Pokemon) float64 {
+	m := calc.BoostMultiplier(poke.Boosts["spe"])
+	if poke.Status == "par" && poke.Ability != "Quick Feet" {
+		if dex.Gen != 0 && dex.Gen < 7 {
+			m *= 0.25
+		} else {
+			m *= 0.5
+		}
+	}
+	if player != nil && player.SideConditions["Tailwind"] > 0 {
+		m *= 2
+	}
+	switch data.ToID(poke.Item) {
+	case "ironball", "machobrace":
+		m *= 0.5
+	}
+	if poke.SpeedBoosted {
+		m *= 1.5
+	}
+	if fields, ok := fieldAbilities[data.ToID(poke.Ability)]; ok && state != nil && fieldActive(state, fields) {
+		m *= 2
+	}
+	return m
+}
+
+// Unmodeled indica si poke pudo tener un factor de Speed que Multiplier no
+// conoce: una habilidad de clima sin revelar con su clima activo, o
+// Unburden sin objeto a la vista. El orden de sus movimientos no dice nada
+// de su Speed.
+func Unmodeled(dex *data.Dex, state *game.BattleState, poke *game.Pokemon) bool {
+	for _, a := range possibleAbilities(dex, poke) {
+		id := data.ToID(a)
+		if id == "unburden" && poke.Item == "" {
+			return true
+		}
+		if fields, ok := fieldAbilities[id]; ok && poke.Ability == "" && fieldActive(state, fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// Effective es el rango de Speed real del Pokémon activo de player.
+func Effective(dex *data.Dex, state *game.BattleState, player *game.Player) Range {
+	if player == nil || player.Active == nil {
+		return Range{}
+	}
+	r := baseRange(dex, state.Format, player.Active)
+	return r.scale(Multiplier(dex, state, player, player.Active))
+}
+
+// OnEntry es el rango de Speed real que tendría poke al entrar desde el
+// banco de player: sin boosts salvo el -1 de Sticky Web, y con parálisis y
+// Tailwind. Sirve para compararlo con Effective del rival.
+func OnEntry(dex *data.Dex, state *game.BattleState, player *game.Player, poke *game.Pokemon, stickyWeb bool) Range {
+	entering := *poke
+	entering.Boosts = nil
+	entering.SpeedBoosted = false
+	if stickyWeb {
+		entering.Boosts = map[string]int{"spe": -1}
+	}
+	return baseRange(dex, state.Format, &entering).scale(Multiplier(dex, state, player, &entering))
+}
+
+func TrickRoom(state *game.BattleState) bool {
+	for effect := range state.FieldEffects {
+		if strings.Contains(effect, "Trick Room") {
+			return true
+		}
+	}
+	return false
+}
+
+// MovesFirst devuelve el jugador que mueve primero entre a y b con
+// movimientos de igual prioridad, o nil si los rangos se solapan.
+func MovesFirst(dex *data.Dex, state *game.BattleState, a, b *game.Player) *game.Player {
+	ra, rb := Effective(dex, state, a), Effective(dex, state, b)
+	if !ra.Known() || !rb.Known() {
+		return nil
+	}
+	faster, slower := a, b
+	switch {
+	case ra.Min > rb.Max:
+	case rb.Min > ra.Max:
+		faster, slower = b, a
+	default:
+		return nil
+	}
+	if TrickRoom(state) {
+		return slower
+	}
+	return faster
+}
+
+// Habilidades y objetos que cambian el orden sin que importe la Speed.
+var (
+	orderAbilities = map[string]bool{
+		"prankster": true, "galewings": true, "triage": true, "quickdraw": true,
+		"stall": true, "myceliummight": true,
+	}
+	orderItems = map[string]bool{"laggingtail": true, "fullincense": true}
+)
+
+// AltersOrder indica si poke pudo moverse antes o después de lo que dice
+// su Speed al usar move: prioridad de habilidad, Quick Claw y similares.
+// Con la habilidad sin revelar alcanza con que la especie pueda tenerla.
+func AltersOrder(dex *data.Dex, poke *game.Pokemon, move string) bool {
+	if poke.PriorityBoosted || orderItems[data.ToID(poke.Item)] {
+		return true
+	}
+	for _, a := range possibleAbilities(dex, poke) {
+		if orderAbilities[data.ToID(a)] {
+			return true
+		}
+	}
+	return false
+}
+
+// Observe actualiza las cotas de Speed con el orden de los movimientos del
+// turno que termina. Sólo compara movimientos del mismo bracket de
+// prioridad; el que movió antes es al menos tan rápido como el otro. Los
+// factores de Speed y Trick Room son los que había al usar cada movimiento,
+// y se saltean los pares en que un factor sin modelar pudo decidir el orden.
+func Observe(dex *data.Dex, state *game.BattleState) {
+	events := state.TurnMoves
+	for i := 0; i < len(events); i++ {
+		for j := i + 1; j < len(events); j++ {
+			first, second := events[i], events[j]
+			if first.Player == second.Player || first.Pokemon == nil || second.Pokemon == nil {
+				continue
+			}
+			if dex.GetMovePriority(first.Move) != dex.GetMovePriority(second.Move) {
+				continue
+			}
+			if first.OrderAltered || second.OrderAltered || first.TrickRoom != second.TrickRoom {
+				continue
+			}
+			if first.SpeedUnmodeled || second.SpeedUnmodeled {
+				continue
+			}
+			if first.SpeedMultiplier <= 0 || second.SpeedMultiplier <= 0 {
+				continue
+			}
+			if first.TrickRoom {
+				first, second = second, first
+			}
+			constrain(dex, state, first, second)
+		}
+	}
+}
+
+func constrain(dex *data.Dex, state *game.BattleState, faster, slower game.MoveEvent) {
+	fm, sm := faster.SpeedMultiplier, slower.SpeedMultiplier
+	sr := baseRange(dex, state.Format, slower.Pokemon)
+
+	if sr.Known() {
+		lower := int(math.Ceil(float64(sr.Min) * sm / fm))
+		if lower > faster.Pokemon.SpeedLower {
+			faster.Pokemon.SpeedLower = lower
+		}
+		theoretical := StatRange(dex, state.Format, faster.Pokemon)
+		if theoretical.Known() && faster.Pokemon.Item == "" && faster.Pokemon.SpeedLower > theoretical.Max {
+			faster.Pokemon.ScarfSuspected = true
+		}
+	}
+	// El rango del más rápido se recalcula porque pudo pasar a incluir Scarf.
+	if fr := baseRange(dex, state.Format, faster.Pokemon); fr.Known() {
+		upper := int(math.Floor(float64(fr.Max) * fm / sm))
+		if slower.Pokemon.SpeedUpper == 0 || upper < slower.Pokemon.SpeedUpper {
+			slower.Pokemon.SpeedUpper = upper
+		}
+	}
+}
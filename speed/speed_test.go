@@ -0,0 +1,165 @@
+package speed
+
+import (
+	"showdown-analizer/data"
+	"showdown-analizer/data/datatest"
+	"showdown-analizer/game"
+	"testing"
+)
+
+// duel arma una batalla gen9ou con un Pokémon activo por lado.
+func duel(a, b *game.Pokemon) *game.BattleState {
+	state := game.NewBattleState()
+	state.Format = "gen9ou"
+	state.Players["p1"] = &game.Player{ID: "p1", Active: a, Team: map[string]*game.Pokemon{a.Name: a}}
+	state.Players["p2"] = &game.Player{ID: "p2", Active: b, Team: map[string]*game.Pokemon{b.Name: b}}
+	return state
+}
+
+// moveEvent arma el evento como lo hace el parser al leer |move|.
+func moveEvent(dex *data.Dex, state *game.BattleState, playerID, move string) game.MoveEvent {
+	player := state.Players[playerID]
+	return game.MoveEvent{
+		Player: playerID, Pokemon: player.Active, Move: move,
+		SpeedMultiplier: Multiplier(dex, state, player, player.Active),
+		TrickRoom:       TrickRoom(state),
+		OrderAltered:    AltersOrder(dex, player.Active, move),
+		SpeedUnmodeled:  Unmodeled(dex, state, player.Active),
+	}
+}
+
+func TestObserve(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	tests := []struct {
+		name string
+		// p1 y p2 son los activos; first mueve primero.
+		p1, p2  game.Pokemon
+		first   string
+		prepare func(*game.BattleState)
+		// Cotas esperadas del Pokémon de p1.
+		lower, upper int
+		scarf        bool
+	}{
+		{
+			name:  "más rápido",
+			p1:    game.Pokemon{Name: "Garchomp", Species: "Garchomp"},
+			p2:    game.Pokemon{Name: "Heatran", Species: "Heatran", Stats: map[string]int{"spe": 250}},
+			first: "p1",
+			lower: 250,
+		},
+		{
+			name:  "más lento",
+			p1:    game.Pokemon{Name: "Garchomp", Species: "Garchomp"},
+			p2:    game.Pokemon{Name: "Heatran", Species: "Heatran", Stats: map[string]int{"spe": 250}},
+			first: "p2",
+			upper: 250,
+		},
+		{
+			name:  "choice scarf",
+			p1:    game.Pokemon{Name: "Heatran", Species: "Heatran"},
+			p2:    game.Pokemon{Name: "Garchomp", Species: "Garchomp", Stats: map[string]int{"spe": 333}},
+			first: "p1",
+			lower: 333,
+			scarf: true,
+		},
+		{
+			name:    "trick room",
+			p1:      game.Pokemon{Name: "Garchomp", Species: "Garchomp"},
+			p2:      game.Pokemon{Name: "Heatran", Species: "Heatran", Stats: map[string]int{"spe": 250}},
+			first:   "p2",
+			prepare: func(s *game.BattleState) { s.FieldEffects["move: Trick Room"] = true },
+			lower:   250,
+		},
+		{
+			name:  "paralizado",
+			p1:    game.Pokemon{Name: "Garchomp", Species: "Garchomp", Status: "par"},
+			p2:    game.Pokemon{Name: "Heatran", Species: "Heatran", Stats: map[string]int{"spe": 120}},
+			first: "p1",
+			lower: 240,
+		},
+		{
+			name:  "iron ball",
+			p1:    game.Pokemon{Name: "Garchomp", Species: "Garchomp", Item: "Iron Ball"},
+			p2:    game.Pokemon{Name: "Heatran", Species: "Heatran", Stats: map[string]int{"spe": 120}},
+			first: "p1",
+			lower: 240,
+		},
+		{
+			name:  "protosynthesis en speed",
+			p1:    game.Pokemon{Name: "Flutter Mane", Species: "Flutter Mane", SpeedBoosted: true},
+			p2:    game.Pokemon{Name: "Regieleki", Species: "Regieleki", Stats: map[string]int{"spe": 450}},
+			first: "p2",
+			upper: 300,
+		},
+		{
+			name:    "chlorophyll revelado con sol",
+			p1:      game.Pokemon{Name: "Venusaur", Species: "Venusaur", Ability: "Chlorophyll"},
+			p2:      game.Pokemon{Name: "Garchomp", Species: "Garchomp", Stats: map[string]int{"spe": 333}},
+			first:   "p1",
+			prepare: func(s *game.BattleState) { s.Weather = "SunnyDay" },
+			lower:   167,
+		},
+		{
+			name:    "posible chlorophyll con sol",
+			p1:      game.Pokemon{Name: "Venusaur", Species: "Venusaur"},
+			p2:      game.Pokemon{Name: "Garchomp", Species: "Garchomp", Stats: map[string]int{"spe": 333}},
+			first:   "p1",
+			prepare: func(s *game.BattleState) { s.Weather = "SunnyDay" },
+		},
+		{
+			name:  "posible unburden",
+			p1:    game.Pokemon{Name: "Hawlucha", Species: "Hawlucha"},
+			p2:    game.Pokemon{Name: "Garchomp", Species: "Garchomp", Stats: map[string]int{"spe": 333}},
+			first: "p1",
+		},
+		{
+			name:  "quick claw",
+			p1:    game.Pokemon{Name: "Heatran", Species: "Heatran", PriorityBoosted: true},
+			p2:    game.Pokemon{Name: "Garchomp", Species: "Garchomp", Stats: map[string]int{"spe": 333}},
+			first: "p1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p1, p2 := tt.p1, tt.p2
+			state := duel(&p1, &p2)
+			if tt.prepare != nil {
+				tt.prepare(state)
+			}
+			order := []string{"p1", "p2"}
+			if tt.first == "p2" {
+				order = []string{"p2", "p1"}
+			}
+			for _, id := range order {
+				state.TurnMoves = append(state.TurnMoves, moveEvent(dex, state, id, "Tackle"))
+			}
+
+			Observe(dex, state)
+			if p1.SpeedLower != tt.lower || p1.SpeedUpper != tt.upper || p1.ScarfSuspected != tt.scarf {
+				t.Errorf("cotas %d-%d scarf=%v, se esperaba %d-%d scarf=%v",
+					p1.SpeedLower, p1.SpeedUpper, p1.ScarfSuspected, tt.lower, tt.upper, tt.scarf)
+			}
+		})
+	}
+}
+
+func TestBaseRange(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	tests := []struct {
+		name string
+		poke game.Pokemon
+		want Range
+	}{
+		{"sin información", game.Pokemon{Species: "Garchomp"}, Range{188, 333}},
+		{"choice scarf", game.Pokemon{Species: "Garchomp", Item: "Choice Scarf"}, Range{282, 499}},
+		{"scarf sospechado", game.Pokemon{Species: "Garchomp", ScarfSuspected: true}, Range{282, 499}},
+		{"acotado", game.Pokemon{Species: "Garchomp", SpeedLower: 250, SpeedUpper: 300}, Range{250, 300}},
+		{"cotas cruzadas", game.Pokemon{Species: "Garchomp", SpeedLower: 320, SpeedUpper: 300}, Range{300, 300}},
+		{"stat conocido", game.Pokemon{Species: "Garchomp", Stats: map[string]int{"spe": 333}}, Range{333, 333}},
+	}
+	for _, tt := range tests {
+		if got := baseRange(dex, "gen9ou", &tt.poke); got != tt.want {
+			t.Errorf("%s: baseRange = %v, se esperaba %v", tt.name, got, tt.want)
+		}
+	}
+}
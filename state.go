@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/parser"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// liveBattle es el estado de una batalla que se está siguiendo por
+// /connect. mu protege state mientras el stream lo actualiza. private
+// indica que la conexión está logueada y el estado trae el |request| del
+// jugador: stats y sets que un espectador no ve.
+type liveBattle struct {
+	mu      sync.Mutex
+	state   *game.BattleState
+	private bool
+}
+
+var (
+	liveMu      sync.Mutex
+	liveBattles = map[string]*liveBattle{}
+)
+
+func liveKey(roomID, perspective string) string {
+	return roomID + "|" + perspective
+}
+
+// newBattleState arma el estado vacío de una conexión a roomID.
+func newBattleState(roomID, perspective string) *game.BattleState {
+	state := game.NewBattleState()
+	state.Format = formatFromRoomID(roomID)
+	state.Perspective = perspective
+	return state
+}
+
+// registerBattle publica el estado de una conexión para /state. Si hay
+// varias conexiones a la misma sala y perspectiva queda la última.
+func registerBattle(roomID, perspective string, state *game.BattleState, private bool) *liveBattle {
+	lb := &liveBattle{state: state, private: private}
+	liveMu.Lock()
+	liveBattles[liveKey(roomID, perspective)] = lb
+	liveMu.Unlock()
+	return lb
+}
+
+func unregisterBattle(roomID, perspective string, lb *liveBattle) {
+	liveMu.Lock()
+	defer liveMu.Unlock()
+	if liveBattles[liveKey(roomID, perspective)] == lb {
+		delete(liveBattles, liveKey(roomID, perspective))
+	}
+}
+
+// handleState devuelve el estado de una batalla en vivo al empezar el turno
+// turn (o el actual si falta) y, con from, los cambios desde ese turno. Por
+// defecto responde el resumen en HTML; con ?json=1 el estado en JSON. El
+// estado de una conexión logueada pide el mismo token que /connect.
+func handleState(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	roomID := query.Get("roomid")
+	if roomID != "" && !strings.HasPrefix(roomID, "battle-") {
+		roomID = "battle-" + roomID
+	}
+	perspective := query.Get("perspective")
+	if perspective == "spectator" {
+		perspective = ""
+	}
+
+	liveMu.Lock()
+	lb := liveBattles[liveKey(roomID, perspective)]
+	liveMu.Unlock()
+	if lb == nil {
+		http.Error(w, "No hay una conexión activa a esa sala", http.StatusNotFound)
+		return
+	}
+	if lb.private && !loginAuthorized(r) {
+		http.Error(w, "Hace falta el token de la cuenta para ver este lado", http.StatusForbidden)
+		return
+	}
+
+	lb.mu.Lock()
+	state := lb.state
+	if t := query.Get("turn"); t != "" {
+		n, err := strconv.Atoi(t)
+		snap, ok := state.AtTurn(n)
+		if err != nil || !ok {
+			lb.mu.Unlock()
+			http.Error(w, fmt.Sprintf("No hay snapshot del turno %q", t), http.StatusNotFound)
+			return
+		}
+		state = snap
+	} else {
+		// El estado en vivo sigue cambiando: se responde una copia.
+		state = state.Snapshot()
+	}
+	var changes []game.Change
+	if f := query.Get("from"); f != "" {
+		n, err := strconv.Atoi(f)
+		prev, ok := lb.state.AtTurn(n)
+		if err != nil || !ok {
+			lb.mu.Unlock()
+			http.Error(w, fmt.Sprintf("No hay snapshot del turno %q", f), http.StatusNotFound)
+			return
+		}
+		changes = game.Diff(prev, state)
+	}
+	lb.mu.Unlock()
+
+	if query.Get("json") != "" {
+		w.Header().Set("Content-Type", "application/json")
+		resp := struct {
+			State   *game.BattleState `json:"state"`
+			Changes []game.Change     `json:"changes,omitempty"`
+		}{state, changes}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("Error escribiendo estado: %v", err)
+		}
+		return
+	}
+
+	writeState(w, data.Current(), state, changes, query.Get("from"))
+}
+
+// writeState escribe el resumen de state con dex y, si from no está vacío,
+// la lista de cambios desde ese turno.
+func writeState(w io.Writer, dex *data.Dex, state *game.BattleState, changes []game.Change, from string) {
+	dex = dex.ForGen(data.GenFromFormat(state.Format))
+	fmt.Fprint(w, parser.RenderBattleState(dex, state))
+	if from != "" {
+		fmt.Fprintf(w, "<div class='turn-diff'><b>Cambios desde el turno %s:</b>", template.HTMLEscapeString(from))
+		if len(changes) == 0 {
+			fmt.Fprint(w, " ninguno")
+		}
+		fmt.Fprint(w, "<ul>")
+		for _, c := range changes {
+			fmt.Fprintf(w, "<li>%s</li>", template.HTMLEscapeString(c.String()))
+		}
+		fmt.Fprint(w, "</ul></div>")
+	}
+}
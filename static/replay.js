@@ -0,0 +1,44 @@
+// Navegación del visor de replays: el servidor manda todos los turnos y
+// acá sólo se muestra uno a la vez.
+function showReplayTurn(viewer, index) {
+    const turns = viewer.querySelectorAll('.replay-turn');
+    if (index < 0 || index >= turns.length) {
+        return;
+    }
+    turns.forEach(t => t.hidden = Number(t.dataset.index) !== index);
+    viewer.querySelectorAll('.timeline-turn').forEach(b => {
+        b.classList.toggle('current', Number(b.dataset.index) === index);
+    });
+    viewer.dataset.current = index;
+}
+
+function currentReplayTurn(viewer) {
+    return Number(viewer.dataset.current || 0);
+}
+
+document.addEventListener('click', function(e) {
+    const viewer = e.target.closest('.replay-report');
+    if (!viewer) {
+        return;
+    }
+    const turn = e.target.closest('.timeline-turn');
+    if (turn) {
+        showReplayTurn(viewer, Number(turn.dataset.index));
+    } else if (e.target.closest('.replay-prev')) {
+        showReplayTurn(viewer, currentReplayTurn(viewer) - 1);
+    } else if (e.target.closest('.replay-next')) {
+        showReplayTurn(viewer, currentReplayTurn(viewer) + 1);
+    }
+});
+
+document.addEventListener('keydown', function(e) {
+    const viewer = document.querySelector('.replay-report');
+    if (!viewer || e.target.matches('input, select, textarea')) {
+        return;
+    }
+    if (e.key === 'ArrowLeft') {
+        showReplayTurn(viewer, currentReplayTurn(viewer) - 1);
+    } else if (e.key === 'ArrowRight') {
+        showReplayTurn(viewer, currentReplayTurn(viewer) + 1);
+    }
+});
@@ -2,7 +2,10 @@ let eventSource;
 let reconnectAttempts = 0;
 const maxReconnects = 10;
 let lastRoomId = null;
+let lastPerspective = 'spectator';
 let battleEnded = false;
+let scrubbing = false;
+let lastSummary = '';
 
 const baseUrl = window.location.hostname === 'localhost'
     ? 'http://localhost:42069'
@@ -23,7 +26,16 @@ function resetUI() {
     reconnectAttempts = 0;
 }
 
-function connectToBattle(roomid) {
+// addToken agrega el token de la cuenta, si se cargó, para ver el
+// |request| de las batallas que juega.
+function addToken(params) {
+    const token = document.getElementById('token-input').value.trim();
+    if (token) {
+        params.set('token', token);
+    }
+}
+
+function connectToBattle(roomid, perspective) {
     const battleLog = document.getElementById('battle-log');
     const suggestionBox = document.getElementById('suggestion-box');
     const connectBtn = document.getElementById('connect-btn');
@@ -31,6 +43,9 @@ function connectToBattle(roomid) {
 
     battleLog.innerHTML = '<p class="placeholder">Conectando...</p>';
     suggestionBox.innerHTML = '';
+    if (roomid !== lastRoomId) {
+        resetScrubber();
+    }
     battleEnded = false;
     reconnectAttempts = 0;
     connectBtn.textContent = 'Conectando...';
@@ -41,8 +56,11 @@ function connectToBattle(roomid) {
         eventSource.close();
     }
 
-    eventSource = new EventSource(`${baseUrl}/connect?roomid=${encodeURIComponent(roomid)}`);
+    const params = new URLSearchParams({roomid: roomid, perspective: perspective});
+    addToken(params);
+    eventSource = new EventSource(`${baseUrl}/connect?${params}`);
     lastRoomId = roomid;
+    lastPerspective = perspective;
 
     eventSource.onmessage = function(event) {
         if (battleLog.querySelector('.placeholder')) {
@@ -60,7 +78,11 @@ function connectToBattle(roomid) {
         }
 
         if (event.data.includes("class='battle-summary'")) {
-            suggestionBox.innerHTML = event.data;
+            lastSummary = event.data;
+            updateScrubber(event.data);
+            if (!scrubbing) {
+                suggestionBox.innerHTML = event.data;
+            }
         } else {
             const p = document.createElement('p');
             p.innerHTML = event.data;
@@ -80,7 +102,7 @@ function connectToBattle(roomid) {
             battleLog.innerHTML += `<p class="warning">Reintentando conexión (${reconnectAttempts}/${maxReconnects})...</p>`;
             setTimeout(() => {
                 if (!battleEnded) {
-                    connectToBattle(lastRoomId);
+                    connectToBattle(lastRoomId, lastPerspective);
                 }
             }, 2000 * reconnectAttempts);
         } else {
@@ -105,7 +127,8 @@ document.getElementById('connect-form').addEventListener('submit', function(e) {
         alert('Por favor ingresa un ID de sala válido');
         return;
     }
-    connectToBattle(roomid);
+    const perspective = document.getElementById('perspective-select').value;
+    connectToBattle(roomid, perspective);
 });
 
 document.getElementById('roomid-input').addEventListener('keypress', function(e) {
@@ -113,3 +136,133 @@ document.getElementById('roomid-input').addEventListener('keypress', function(e)
         document.getElementById('connect-form').dispatchEvent(new Event('submit'));
     }
 });
+
+document.getElementById('battles-list').addEventListener('click', function(e) {
+    const btn = e.target.closest('.watch-btn');
+    if (!btn) {
+        return;
+    }
+    const roomInput = document.getElementById('roomid-input');
+    roomInput.value = btn.dataset.roomid;
+    connectToBattle(btn.dataset.roomid, document.getElementById('perspective-select').value);
+});
+
+let followSource;
+
+// setFollowStatus muestra text en el estado del seguimiento. Es texto: el
+// usuario y la sala no se interpretan como HTML.
+function setFollowStatus(className, text) {
+    const followStatus = document.getElementById('follow-status');
+    followStatus.replaceChildren();
+    if (!text) {
+        return;
+    }
+    const p = document.createElement('p');
+    if (className) {
+        p.className = className;
+    }
+    p.textContent = text;
+    followStatus.appendChild(p);
+}
+
+function followUser(user) {
+    const followBtn = document.getElementById('follow-btn');
+
+    if (followSource) {
+        followSource.close();
+        followSource = null;
+    }
+    if (!user) {
+        setFollowStatus('', '');
+        followBtn.textContent = 'Seguir';
+        return;
+    }
+
+    followSource = new EventSource(`${baseUrl}/follow?user=${encodeURIComponent(user)}`);
+    followBtn.textContent = 'Dejar de seguir';
+
+    followSource.onmessage = function(event) {
+        setFollowStatus('', event.data);
+    };
+
+    followSource.addEventListener('battle', function(event) {
+        const roomid = event.data;
+        setFollowStatus('success', `${user} empezó ${roomid}, conectando...`);
+        document.getElementById('roomid-input').value = roomid;
+        connectToBattle(roomid, document.getElementById('perspective-select').value);
+    });
+
+    followSource.onerror = function() {
+        if (followSource.readyState === EventSource.CLOSED) {
+            setFollowStatus('error', 'No se pudo seguir al usuario; puede que ya se siga al máximo de usuarios.');
+            followSource = null;
+            followBtn.textContent = 'Seguir';
+            return;
+        }
+        setFollowStatus('warning', 'Se perdió la conexión del seguimiento, reintentando...');
+    };
+}
+
+document.getElementById('follow-form').addEventListener('submit', function(e) {
+    e.preventDefault();
+    if (followSource) {
+        followUser('');
+        return;
+    }
+    followUser(document.getElementById('follow-input').value.trim());
+});
+
+function resetScrubber() {
+    scrubbing = false;
+    lastSummary = '';
+    document.getElementById('turn-scrubber').hidden = true;
+    document.getElementById('turn-range').max = 1;
+}
+
+// updateScrubber estira el scrubber hasta el turno del último resumen.
+function updateScrubber(summary) {
+    const match = summary.match(/<h3>Turno: (\d+)<\/h3>/);
+    if (!match || Number(match[1]) < 1) {
+        return;
+    }
+    const range = document.getElementById('turn-range');
+    range.max = match[1];
+    if (!scrubbing) {
+        range.value = match[1];
+        document.getElementById('turn-label').textContent = `Turno ${match[1]} (en vivo)`;
+    }
+    document.getElementById('turn-scrubber').hidden = false;
+}
+
+function showTurn(turn) {
+    const suggestionBox = document.getElementById('suggestion-box');
+    const params = new URLSearchParams({roomid: lastRoomId, perspective: lastPerspective, turn: turn});
+    if (turn > 1) {
+        params.set('from', turn - 1);
+    }
+    addToken(params);
+    scrubbing = true;
+    document.getElementById('turn-label').textContent = `Turno ${turn}`;
+    fetch(`${baseUrl}/state?${params}`)
+        .then(resp => resp.ok ? resp.text() : Promise.reject(resp.statusText))
+        .then(html => {
+            if (scrubbing) {
+                suggestionBox.innerHTML = html;
+            }
+        })
+        .catch(err => {
+            suggestionBox.innerHTML = `<p class="error">No se pudo cargar el turno ${turn}: ${err}</p>`;
+        });
+}
+
+document.getElementById('turn-range').addEventListener('change', function() {
+    showTurn(Number(this.value));
+});
+
+document.getElementById('live-btn').addEventListener('click', function() {
+    scrubbing = false;
+    const range = document.getElementById('turn-range');
+    range.value = range.max;
+    document.getElementById('turn-label').textContent = `Turno ${range.max} (en vivo)`;
+    document.getElementById('suggestion-box').innerHTML = lastSummary;
+});
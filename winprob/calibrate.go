@@ -0,0 +1,90 @@
+package winprob
+
+import "math"
+
+// Sample es el estado de una batalla terminada al empezar un turno, con el
+// resultado final.
+type Sample struct {
+	Features []float64
+	P1Won    bool
+}
+
+// Fit ajusta una regresión logística por descenso de gradiente sobre
+// samples, con una regularización L2 chica para que las features raras no
+// se disparen.
+func Fit(samples []Sample, epochs int, rate float64) Weights {
+	const l2 = 0.001
+	w := make([]float64, len(FeatureNames))
+	bias := 0.0
+	n := float64(len(samples))
+	for epoch := 0; epoch < epochs && n > 0; epoch++ {
+		grad := make([]float64, len(w))
+		gradBias := 0.0
+		for _, s := range samples {
+			z := bias
+			for i := range w {
+				z += w[i] * s.Features[i]
+			}
+			diff := 1 / (1 + math.Exp(-z))
+			if s.P1Won {
+				diff--
+			}
+			for i := range w {
+				grad[i] += diff * s.Features[i]
+			}
+			gradBias += diff
+		}
+		for i := range w {
+			w[i] -= rate * (grad[i]/n + l2*w[i])
+		}
+		bias -= rate * gradBias / n
+	}
+
+	weights := Weights{Bias: bias, Features: map[string]float64{}}
+	for i, name := range FeatureNames {
+		weights.Features[name] = w[i]
+	}
+	return weights
+}
+
+// Bucket es un tramo de la curva de calibración: cuántas muestras cayeron
+// con probabilidad predicha en [Low, High) y qué fracción ganó p1.
+type Bucket struct {
+	Low, High float64
+	Count     int
+	Predicted float64
+	Observed  float64
+}
+
+// Calibration reparte las predicciones de w en n tramos y devuelve también
+// el Brier score (error cuadrático medio de la probabilidad).
+func Calibration(w *Weights, samples []Sample, n int) ([]Bucket, float64) {
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		buckets[i].Low = float64(i) / float64(n)
+		buckets[i].High = float64(i+1) / float64(n)
+	}
+	brier := 0.0
+	for _, s := range samples {
+		p := w.Predict(s.Features)
+		outcome := 0.0
+		if s.P1Won {
+			outcome = 1
+		}
+		brier += (p - outcome) * (p - outcome)
+		b := &buckets[min(int(p*float64(n)), n-1)]
+		b.Count++
+		b.Predicted += p
+		b.Observed += outcome
+	}
+	for i := range buckets {
+		if c := float64(buckets[i].Count); c > 0 {
+			buckets[i].Predicted /= c
+			buckets[i].Observed /= c
+		}
+	}
+	if len(samples) > 0 {
+		brier /= float64(len(samples))
+	}
+	return buckets, brier
+}
@@ -0,0 +1,91 @@
+package winprob
+
+import (
+	"math"
+	"showdown-analizer/analysis"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"showdown-analizer/speed"
+)
+
+// Nombres de las features en el orden en que las devuelve Features. Todas
+// se miden desde p1: positivas cuando p1 va mejor.
+var FeatureNames = []string{"hp", "kos", "speed", "hazards", "matchup"}
+
+// Sin |teamsize| se asume un equipo de seis.
+const defaultTeamSize = 6
+
+// Features describe state con números comparables entre batallas.
+func Features(dex *data.Dex, state *game.BattleState) []float64 {
+	p1, p2 := state.Players["p1"], state.Players["p2"]
+	f := make([]float64, len(FeatureNames))
+	if p1 == nil || p2 == nil {
+		return f
+	}
+	f[0] = remainingHP(p1) - remainingHP(p2)
+	f[1] = float64(fainted(p2)-fainted(p1)) / defaultTeamSize
+	switch speed.MovesFirst(dex, state, p1, p2) {
+	case p1:
+		f[2] = 1
+	case p2:
+		f[2] = -1
+	}
+	f[3] = benchHazards(dex, p2) - benchHazards(dex, p1)
+	if p1.Active != nil && p2.Active != nil && !p1.Active.Fainted && !p2.Active.Fainted {
+		f[4] = math.Max(-1, math.Min(1, analysis.Matchup(dex, state.Format, p1.Active, p2.Active)/4))
+	}
+	return f
+}
+
+func teamSize(player *game.Player) int {
+	size := player.TeamSize
+	if size == 0 {
+		size = defaultTeamSize
+	}
+	return max(size, len(player.Team))
+}
+
+// remainingHP es la vida media del equipo; los no revelados cuentan como
+// sanos.
+func remainingHP(player *game.Player) float64 {
+	total := 0.0
+	for _, poke := range player.Team {
+		switch {
+		case poke.Fainted:
+		case poke.MaxHP > 0:
+			total += float64(poke.HP) / float64(poke.MaxHP)
+		default:
+			total++
+		}
+	}
+	total += float64(teamSize(player) - len(player.Team))
+	return total / float64(teamSize(player))
+}
+
+func fainted(player *game.Player) int {
+	count := 0
+	for _, poke := range player.Team {
+		if poke.Fainted {
+			count++
+		}
+	}
+	return count
+}
+
+// benchHazards es el daño medio que recibirían al entrar los Pokémon vivos
+// del banco de player.
+func benchHazards(dex *data.Dex, player *game.Player) float64 {
+	total, count := 0.0, 0
+	for _, poke := range player.Team {
+		if poke.Fainted || poke == player.Active || len(poke.Type) == 0 {
+			continue
+		}
+		damage, _ := analysis.HazardDamage(dex, player, poke)
+		total += damage
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
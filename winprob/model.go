@@ -0,0 +1,56 @@
+package winprob
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"showdown-analizer/data"
+	"showdown-analizer/game"
+	"sync/atomic"
+)
+
+// Weights es una regresión logística sobre Features.
+type Weights struct {
+	Bias     float64            `json:"bias"`
+	Features map[string]float64 `json:"features"`
+}
+
+// DefaultWeights son pesos a mano para cuando no se cargó una calibración.
+var DefaultWeights = Weights{
+	Features: map[string]float64{"hp": 4, "kos": 3, "speed": 0.3, "hazards": 2, "matchup": 0.3},
+}
+
+var current atomic.Pointer[Weights]
+
+func init() {
+	current.Store(&DefaultWeights)
+}
+
+// LoadWeights reemplaza los pesos por los de un JSON generado con
+// cmd/calibrate.
+func LoadWeights(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var w Weights
+	if err := json.Unmarshal(raw, &w); err != nil {
+		return fmt.Errorf("pesos inválidos en %s: %w", path, err)
+	}
+	current.Store(&w)
+	return nil
+}
+
+func (w *Weights) Predict(features []float64) float64 {
+	z := w.Bias
+	for i, name := range FeatureNames {
+		z += w.Features[name] * features[i]
+	}
+	return 1 / (1 + math.Exp(-z))
+}
+
+// Probability es la probabilidad de que gane p1 en state.
+func Probability(dex *data.Dex, state *game.BattleState) float64 {
+	return current.Load().Predict(Features(dex, state))
+}
@@ -0,0 +1,103 @@
+package winprob
+
+import (
+	"math"
+	"showdown-analizer/data/datatest"
+	"showdown-analizer/game"
+	"testing"
+)
+
+// battle arma una batalla gen9ou: p1 con Garchomp dañado y un banco con
+// Stealth Rock, p2 con Heatran sano y un debilitado.
+func battle(swap bool) *game.BattleState {
+	chomp := &game.Pokemon{Name: "Garchomp", Species: "Garchomp", Type: []string{"Dragon", "Ground"}, HP: 40, MaxHP: 100}
+	zard := &game.Pokemon{Name: "Charizard", Species: "Charizard", Type: []string{"Fire", "Flying"}, HP: 100, MaxHP: 100}
+	tran := &game.Pokemon{Name: "Heatran", Species: "Heatran", Type: []string{"Fire", "Steel"}, HP: 100, MaxHP: 100}
+	lost := &game.Pokemon{Name: "Clefable", Species: "Clefable", Type: []string{"Fairy"}, Fainted: true}
+
+	a := &game.Player{
+		Active:         chomp,
+		Team:           map[string]*game.Pokemon{chomp.Name: chomp, zard.Name: zard},
+		SideConditions: map[string]int{"Stealth Rock": 1},
+	}
+	b := &game.Player{Active: tran, Team: map[string]*game.Pokemon{tran.Name: tran, lost.Name: lost}}
+	if swap {
+		a, b = b, a
+	}
+	a.ID, b.ID = "p1", "p2"
+	state := game.NewBattleState()
+	state.Format = "gen9ou"
+	state.Players["p1"], state.Players["p2"] = a, b
+	return state
+}
+
+func TestPredictRange(t *testing.T) {
+	w := DefaultWeights
+	for _, f := range [][]float64{
+		{0, 0, 0, 0, 0},
+		{1, 1, 1, 1, 1},
+		{-1, -1, -1, -1, -1},
+		{100, 100, 100, 100, 100},
+		{-100, -100, -100, -100, -100},
+	} {
+		if p := w.Predict(f); p < 0 || p > 1 || math.IsNaN(p) {
+			t.Errorf("Predict(%v) = %v, se esperaba un valor entre 0 y 1", f, p)
+		}
+	}
+	if p := w.Predict([]float64{0, 0, 0, 0, 0}); p != 0.5 {
+		t.Errorf("sin ventaja Predict = %v, se esperaba 0.5", p)
+	}
+	if w.Predict([]float64{0.5, 0, 0, 0, 0}) <= 0.5 || w.Predict([]float64{-0.5, 0, 0, 0, 0}) >= 0.5 {
+		t.Error("más vida para p1 debería subir su probabilidad")
+	}
+}
+
+func TestProbabilitySymmetric(t *testing.T) {
+	dex := datatest.Dex(t, 9)
+	p := Probability(dex, battle(false))
+	swapped := Probability(dex, battle(true))
+	if p <= 0 || p >= 1 {
+		t.Fatalf("probabilidad %v fuera de (0, 1)", p)
+	}
+	if math.Abs(p+swapped-1) > 1e-9 {
+		t.Errorf("al cambiar los lados: %v + %v, se esperaba que sumaran 1", p, swapped)
+	}
+}
+
+// dataset son muestras con sólo la feature de vida, en que p1 gana en la
+// fracción de batallas que da una logística de pendiente 4.
+func dataset() []Sample {
+	var samples []Sample
+	for hp := -0.8; hp <= 0.81; hp += 0.2 {
+		wins := int(math.Round(10 / (1 + math.Exp(-4*hp))))
+		for i := 0; i < 10; i++ {
+			samples = append(samples, Sample{Features: []float64{hp, 0, 0, 0, 0}, P1Won: i < wins})
+		}
+	}
+	return samples
+}
+
+func TestFitCalibration(t *testing.T) {
+	samples := dataset()
+	w := Fit(samples, 3000, 1)
+	if hp := w.Features["hp"]; hp < 3 || hp > 5 {
+		t.Errorf("peso de hp %v, se esperaba cerca de 4", hp)
+	}
+
+	buckets, brier := Calibration(&w, samples, 5)
+	// Con estos datos el Brier del ajuste ronda 0.13; sin información
+	// (siempre 0.5) es 0.25.
+	if brier > 0.15 {
+		t.Errorf("Brier %v, se esperaba a lo sumo 0.15", brier)
+	}
+	total := 0
+	for _, b := range buckets {
+		total += b.Count
+		if b.Count > 0 && math.Abs(b.Predicted-b.Observed) > 0.1 {
+			t.Errorf("tramo [%v, %v): predicho %v, observado %v", b.Low, b.High, b.Predicted, b.Observed)
+		}
+	}
+	if total != len(samples) {
+		t.Errorf("%d muestras en los tramos, se esperaban %d", total, len(samples))
+	}
+}